@@ -0,0 +1,49 @@
+package gci
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/daixiang0/gci/pkg/cache"
+	"github.com/daixiang0/gci/pkg/config"
+	"github.com/daixiang0/gci/pkg/gci"
+	"github.com/daixiang0/gci/pkg/log"
+)
+
+// cacheCmd represents the cache command
+func (e *Executor) initCache() {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manages the cache of already formatted files",
+		Long:  "Files which are already formatted are remembered by their content, the configuration and the version of gci, so later runs can skip them. Pass --no-cache to the formatting commands to bypass the cache.",
+	}
+	cacheCmd.AddCommand(&cobra.Command{
+		Use:   "clean",
+		Short: "Removes all cached results",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := cache.DefaultDir()
+			if err != nil {
+				return err
+			}
+			return cache.Clean(dir)
+		},
+	})
+	e.rootCmd.AddCommand(cacheCmd)
+}
+
+// openCache returns the cache for cfg, or nil if it is not available.
+func (e *Executor) openCache(cfg config.Config) *cache.Cache {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		log.L().Debug(fmt.Sprintf("Cache disabled: %v", err))
+		return nil
+	}
+	c, err := cache.Open(dir, gci.CacheKey(cfg, e.version))
+	if err != nil {
+		log.L().Debug(fmt.Sprintf("Cache disabled: %v", err))
+		return nil
+	}
+	return c
+}
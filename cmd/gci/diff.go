@@ -1,16 +1,34 @@
 package gci
 
 import (
+	"os"
+
+	"github.com/daixiang0/gci/pkg/config"
 	"github.com/daixiang0/gci/pkg/gci"
 )
 
 // diffCmd represents the diff command
 func (e *Executor) initDiff() {
-	e.newGciCommand(
+	var color *string
+	var sideBySide *bool
+	cmd := e.newGciCommand(
 		"diff path...",
 		"Prints a git style diff to STDOUT",
 		"Diff prints a patch in the style of the diff tool that contains the required changes to the file to make it adhere to the specified formatting.",
 		[]string{},
 		true,
-		gci.DiffFormattedFiles)
+		false,
+		func(args []string, gciCfg config.Config) error {
+			colorMode, err := gci.ParseColorMode(*color)
+			if err != nil {
+				return err
+			}
+			return gci.DiffFormattedFilesWithOptions(args, gciCfg, gci.DiffOptions{
+				Color:      colorMode.Enabled(os.Stdout),
+				SideBySide: *sideBySide,
+			})
+		})
+
+	color = cmd.Flags().String("color", string(gci.ColorAuto), "Colorize the diff: auto, always or never. auto only colors terminal output and honours NO_COLOR")
+	sideBySide = cmd.Flags().Bool("side-by-side", false, "Show the original and the formatted import block in two columns, labeled with their sections")
 }
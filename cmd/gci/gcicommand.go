@@ -1,19 +1,32 @@
 package gci
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/spf13/cobra"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/daixiang0/gci/pkg/config"
+	"github.com/daixiang0/gci/pkg/gci"
+	"github.com/daixiang0/gci/pkg/io"
 	"github.com/daixiang0/gci/pkg/log"
+	"github.com/daixiang0/gci/pkg/parse"
+	"github.com/daixiang0/gci/pkg/rules"
 	"github.com/daixiang0/gci/pkg/section"
 )
 
 type processingFunc = func(args []string, gciCfg config.Config) error
 
-func (e *Executor) newGciCommand(use, short, long string, aliases []string, stdInSupport bool, processingFunc processingFunc) *cobra.Command {
-	var noInlineComments, noPrefixComments, skipGenerated, skipVendor, customOrder, noLexOrder, debug *bool
-	var sectionStrings, sectionSeparatorStrings *[]string
+// newGciCommand registers a formatting subcommand. keepGoing decides whether the command processes all files
+// before reporting errors by default, it can be overridden with --keep-going and --fail-fast.
+func (e *Executor) newGciCommand(use, short, long string, aliases []string, stdInSupport, keepGoing bool, processingFunc processingFunc) *cobra.Command {
+	var noInlineComments, noPrefixComments, skipGenerated, skipVendor, customOrder, noLexOrder, embedded, noMerge, dedupe, keepGoingFlag, failFast, noCache, debug *bool
+	var lineEndings, generatedFiles, sortMode, filesFrom, stdinFilename *string
+	var jobs *int
+	var sectionStrings, sectionSeparatorStrings, sectionSortStrings, subgroupByModule, aliasStrings, ruleStrings *[]string
 	cmd := cobra.Command{
 		Use:               use,
 		Aliases:           aliases,
@@ -29,19 +42,80 @@ func (e *Executor) newGciCommand(use, short, long string, aliases []string, stdI
 				SkipVendor:       *skipVendor,
 				CustomOrder:      *customOrder,
 				NoLexOrder:       *noLexOrder,
+				FormatEmbedded:   *embedded,
+				NoMerge:          *noMerge,
+				Dedupe:           *dedupe,
+			}
+			aliases, err := parseAliases(*aliasStrings)
+			if err != nil {
+				return err
+			}
+			importRules, err := parseRules(*ruleStrings)
+			if err != nil {
+				return err
+			}
+			sectionSort, err := parseSectionSort(*sectionSortStrings)
+			if err != nil {
+				return err
 			}
-			gciCfg, err := config.YamlConfig{Cfg: fmtCfg, SectionStrings: *sectionStrings, SectionSeparatorStrings: *sectionSeparatorStrings}.Parse()
+			gciCfg, err := config.YamlConfig{
+				Cfg:                     fmtCfg,
+				SectionStrings:          *sectionStrings,
+				SectionSeparatorStrings: *sectionSeparatorStrings,
+				LineEndings:             *lineEndings,
+				GeneratedFiles:          *generatedFiles,
+				Aliases:                 aliases,
+				Rules:                   importRules,
+				Sort:                    *sortMode,
+				SectionSort:             sectionSort,
+				SubgroupByModule:        *subgroupByModule,
+			}.Parse()
 			if err != nil {
 				return err
 			}
+			if *jobs < 0 {
+				return fmt.Errorf("invalid number of jobs %d: must not be negative", *jobs)
+			}
+			gciCfg.Jobs = *jobs
+			gciCfg.KeepGoing = keepGoing
+			if cmd.Flags().Changed("keep-going") {
+				gciCfg.KeepGoing = *keepGoingFlag
+			}
+			if cmd.Flags().Changed("fail-fast") {
+				gciCfg.KeepGoing = !*failFast
+			}
 			if *debug {
 				log.SetLevel(zapcore.DebugLevel)
 			}
-			return processingFunc(args, *gciCfg)
+			gciCfg.StdinFilename = *stdinFilename
+			args, err = readFileLists(args, *filesFrom, gciCfg)
+			if err != nil {
+				return err
+			}
+			if !*noCache {
+				gciCfg.Cache = e.openCache(*gciCfg)
+			}
+			err = processingFunc(args, *gciCfg)
+			if gci.PrintErrorSummary(os.Stderr, err) {
+				// the summary already lists every failure
+				cmd.SilenceErrors = true
+				cmd.SilenceUsage = true
+			}
+			var violations *gci.ViolationsError
+			if errors.As(err, &violations) {
+				// the imports that break the rules are listed, only the exit status is missing
+				cmd.SilenceUsage = true
+			}
+			return err
 		},
 	}
 	if !stdInSupport {
-		cmd.Args = cobra.MinimumNArgs(1)
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			if *filesFrom != "" {
+				return nil
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		}
 	}
 
 	// register command as subcommand
@@ -49,21 +123,43 @@ func (e *Executor) newGciCommand(use, short, long string, aliases []string, stdI
 
 	debug = cmd.Flags().BoolP("debug", "d", false, "Enables debug output from the formatter")
 
-	sectionHelp := `Sections define how inputs will be processed. Section names are case-insensitive and may contain parameters in (). The section order is standard > default > custom > blank > dot > alias > localmodule. The default value is [standard,default].
-standard - standard section that Go provides officially, like "fmt"
+	sectionHelp := `Sections define how inputs will be processed. Section names are case-insensitive and may contain parameters in (). The section order is cgo > standard > default > custom > blank > dot > alias > localmodule. The default value is [standard,default].
+standard - standard section that Go provides officially, like "fmt". standard(gomod) uses the standard library of the go directive in the go.mod of the file
 Prefix(github.com/daixiang0) - custom section, groups all imports with the specified Prefix. Imports will be matched to the longest Prefix. Multiple custom prefixes may be provided, they will be rendered as distinct sections separated by newline. You can regroup multiple prefixes by separating them with comma: Prefix(github.com/daixiang0,gitlab.com/daixiang0,daixiang0)
 default - default section, contains all rest imports
 blank - blank section, contains all blank imports.
 dot - dot section, contains all dot imports.
 alias - alias section, contains all alias imports.
-localmodule: localmodule section, contains all imports from local packages`
+cgo - the place of the import "C" declarations, they go in front of the other imports without it.
+localmodule: localmodule section, contains all imports from local packages. localmodule(self) only contains the module of the file, localmodule(workspace) all modules of its workspace and localmodule(replace) also the modules replaced by local directories`
 
 	skipGenerated = cmd.Flags().Bool("skip-generated", false, "Skip generated files")
+	generatedFiles = cmd.Flags().String("generated-files", string(config.GeneratedFilesLax), "How --skip-generated detects generated files: strict only skips files with a // Code generated ... DO NOT EDIT. comment before the package clause, lax also files mentioning markers like \"do not edit\" anywhere")
 	skipVendor = cmd.Flags().Bool("skip-vendor", false, "Skip files inside vendor directory")
 
 	customOrder = cmd.Flags().Bool("custom-order", false, "Enable custom order of sections")
 	noLexOrder = cmd.Flags().Bool("no-lex-order", false, "Drops lexical ordering for custom sections")
+	dedupe = cmd.Flags().Bool("dedupe", false, "Remove duplicate imports and aliases that equal the package name, like yaml \"gopkg.in/yaml.v3\"")
+	noMerge = cmd.Flags().Bool("no-merge", false, "Format every import declaration on its own instead of merging them into one")
+	embedded = cmd.Flags().Bool("embedded", false, "Also format Go code blocks in Markdown files and .go files in txtar archives")
+	lineEndings = cmd.Flags().String("line-endings", string(config.LineEndingsPreserve), "Line endings of the formatted files: preserve, lf or crlf")
+	jobs = cmd.Flags().IntP("jobs", "j", 0, "Number of files processed in parallel, 0 uses GOMAXPROCS")
+	keepGoingFlag = cmd.Flags().Bool("keep-going", keepGoing, "Process all files even if some of them fail and report every failure at the end")
+	failFast = cmd.Flags().Bool("fail-fast", !keepGoing, "Stop at the first file that fails")
+	cmd.MarkFlagsMutuallyExclusive("keep-going", "fail-fast")
+	filesFrom = cmd.Flags().String("files-from", "", "Also process the paths listed in this file, separated by newlines or NUL bytes. - or a - argument reads the list from STDIN")
+	if stdInSupport {
+		stdinFilename = cmd.Flags().String("stdin-filename", "", "Path of the file whose content is read from STDIN, used for module detection, --skip-vendor and diff headers")
+	} else {
+		stdinFilename = new(string)
+	}
+	noCache = cmd.Flags().Bool("no-cache", false, "Do not skip files which were already formatted in a previous run")
 	sectionStrings = cmd.Flags().StringArrayP("section", "s", section.DefaultSections().String(), sectionHelp)
+	sortMode = cmd.Flags().String("sort", string(parse.SortLexical), "Order of the imports within a section: lexical sorts by path like gofmt, natural sorts numbers by value (v2 before v10), case-insensitive ignores the case of the paths, by-alias sorts by alias or package name")
+	subgroupByModule = cmd.Flags().StringArray("subgroup-by-module", nil, "Section whose imports are separated by blank lines into the modules required by the go.mod of the file, or by their host or repository otherwise, e.g. default")
+	sectionSortStrings = cmd.Flags().StringArray("section-sort", nil, "Order of the imports within one section as section=mode, e.g. prefix(github.com/daixiang0)=natural, overrides --sort")
+	ruleStrings = cmd.Flags().StringArray("rule", nil, "Import rule reported by list: dot bans dot imports outside of tests, blank bans blank imports outside of package main and init-only files, package(path) or package(path,replacement) bans a package like io/ioutil, blankcomment bans blank imports without comment, blankcomment(embed,tests) exempts embed and _test.go files")
+	aliasStrings = cmd.Flags().StringArray("alias", nil, "Canonical alias of an import path as path=alias, e.g. k8s.io/apimachinery/pkg/apis/meta/v1=metav1. Imports with another name are renamed together with their selectors")
 
 	// deprecated
 	noInlineComments = cmd.Flags().Bool("NoInlineComments", false, "Drops inline comments while formatting")
@@ -76,3 +172,82 @@ localmodule: localmodule section, contains all imports from local packages`
 
 	return &cmd
 }
+
+// parseAliases parses the path=alias pairs of the --alias flags.
+func parseAliases(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	aliases := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		importPath, alias, found := strings.Cut(pair, "=")
+		if !found || importPath == "" {
+			return nil, fmt.Errorf("invalid alias %q, must be path=alias", pair)
+		}
+		aliases[importPath] = alias
+	}
+	return aliases, nil
+}
+
+// parseSectionSort parses the section=mode pairs of the --section-sort flags.
+func parseSectionSort(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	modes := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid section sort mode %q, must be section=mode", pair)
+		}
+		modes[pair[:i]] = pair[i+1:]
+	}
+	return modes, nil
+}
+
+// parseRules parses the rules of the --rule flags.
+func parseRules(in []string) ([]rules.Rule, error) {
+	var importRules []rules.Rule
+	for _, s := range in {
+		r, err := rules.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		importRules = append(importRules, r)
+	}
+	return importRules, nil
+}
+
+// readFileLists replaces a - argument with the paths listed on STDIN and adds the paths listed in the filesFrom file.
+// Listed paths that can not be formatted are skipped.
+func readFileLists(args []string, filesFrom string, cfg *config.Config) ([]string, error) {
+	var paths, lists []string
+	for _, arg := range args {
+		if arg == "-" {
+			lists = append(lists, arg)
+		} else {
+			paths = append(paths, arg)
+		}
+	}
+	if filesFrom != "" {
+		lists = append(lists, filesFrom)
+	}
+
+	stdinRead := false
+	for _, list := range lists {
+		if list == "-" {
+			if stdinRead {
+				continue
+			}
+			stdinRead = true
+			// STDIN holds the list and no Go source
+			cfg.NoStdin = true
+		}
+		listed, err := io.ReadFileList(list)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, gci.FilterFileList(listed, *cfg)...)
+	}
+	return paths, nil
+}
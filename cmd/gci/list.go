@@ -9,8 +9,9 @@ func (e *Executor) initList() {
 	e.newGciCommand(
 		"list path...",
 		"Prints filenames that need to be formatted to STDOUT",
-		"Prints the filenames that need to be formatted. If you want to show the diff use diff instead, and if you want to apply the changes use write instead",
+		"Prints the filenames that need to be formatted, followed by the imports that break the rules, which make it exit with status 1. If you want to show the diff use diff instead, and if you want to apply the changes use write instead",
 		[]string{},
 		false,
+		true,
 		gci.ListUnFormattedFiles)
 }
@@ -12,5 +12,6 @@ func (e *Executor) initPrint() {
 		"Print outputs the formatted file. If you want to apply the changes to a file use write instead!",
 		[]string{"output"},
 		true,
+		false,
 		gci.PrintFormattedFiles)
 }
@@ -17,13 +17,14 @@ type Executor struct {
 	diffMode   *bool
 	writeMode  *bool
 	localFlags *[]string
+	version    string
 }
 
 func NewExecutor(version string) *Executor {
 	log.InitLogger()
 	defer log.L().Sync()
 
-	e := Executor{}
+	e := Executor{version: version}
 	rootCmd := cobra.Command{
 		Use:   "gci [-diff | -write] [--local localPackageURLs] path...",
 		Short: "Gci controls golang package import order and makes it always deterministic",
@@ -43,6 +44,7 @@ func NewExecutor(version string) *Executor {
 	e.initPrint()
 	e.initWrite()
 	e.initList()
+	e.initCache()
 	return &e
 }
 
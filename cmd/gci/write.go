@@ -12,5 +12,6 @@ func (e *Executor) initWrite() {
 		"Write modifies the specified files in-place",
 		[]string{"overwrite"},
 		false,
+		false,
 		gci.WriteFormattedFiles)
 }
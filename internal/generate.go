@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"go/format"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"text/template"
@@ -20,6 +23,8 @@ import (
 
 const outputFile = "../pkg/section/standard_list.go"
 
+const versionsOutputFile = "../pkg/section/standard_versions.go"
+
 const stdTemplate = `
 package section
 
@@ -33,6 +38,23 @@ var standardPackages = map[string]struct{}{
 
 `
 
+const versionsTemplate = `
+package section
+
+// Code generated based on the api files of {{ .Version }}. DO NOT EDIT.
+
+// newestStandardVersion is the newest Go 1 minor version of standardPackageVersions.
+const newestStandardVersion = {{ .Newest }}
+
+// standardPackageVersions holds the Go 1 minor version every standard package was added in.
+var standardPackageVersions = map[string]int{
+{{- range $pkg := .Packages }}
+		"{{ $pkg.Path }}":  {{ $pkg.Version }},
+{{- end}}
+}
+
+`
+
 func main() {
 	err := generate()
 	if err != nil {
@@ -86,6 +108,29 @@ windows	arm
 windows	arm64`
 
 func generate() error {
+	pkgs, err := loadStd("arenas,boringcrypto,synctest,jsonv2")
+	if err != nil {
+		return err
+	}
+
+	err = writeTemplate(outputFile, stdTemplate, map[string]interface{}{
+		"Packages": pkgs,
+		"Version":  runtime.Version(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// the versioned list only holds released packages, experiments are not part of any Go version
+	released, err := loadStd("")
+	if err != nil {
+		return err
+	}
+	return generateVersions(released)
+}
+
+// loadStd returns the public standard packages of all platforms with the given experiments enabled.
+func loadStd(experiments string) ([]string, error) {
 	var all []*packages.Package
 
 	writeLock := sync.Mutex{}
@@ -101,7 +146,7 @@ func generate() error {
 
 			pkgs, err := packages.Load(&packages.Config{
 				Mode: packages.NeedName,
-				Env:  append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch, "GOEXPERIMENT=arenas,boringcrypto,synctest,jsonv2"),
+				Env:  append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch, "GOEXPERIMENT="+experiments),
 			}, "std")
 			if err != nil {
 				return err
@@ -117,7 +162,7 @@ func generate() error {
 	}
 
 	if err := g.Wait(); err != nil {
-		return err
+		return nil, err
 	}
 
 	uniquePkgs := make(map[string]struct{})
@@ -135,19 +180,102 @@ func generate() error {
 	}
 
 	slices.Sort(pkgs)
+	return pkgs, nil
+}
+
+// firstReleases holds the Go 1 minor version of the standard packages without exported API in the release they were
+// added in. The api files only list them from their first exported API on, or not at all, like unsafe and syscall/js.
+var firstReleases = map[string]int{
+	"runtime/cgo":  0,
+	"runtime/race": 1,
+	"syscall/js":   11,
+	"time/tzdata":  15,
+	"unsafe":       0,
+}
 
-	file, err := os.Create(outputFile)
+type packageVersion struct {
+	Path    string
+	Version int
+}
+
+// generateVersions writes the Go version every standard package was added in, based on the api files of the toolchain,
+// which list the exported API of every Go 1 release.
+func generateVersions(pkgs []string) error {
+	goroot, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		return err
+	}
+	apiFiles, err := filepath.Glob(filepath.Join(strings.TrimSpace(string(goroot)), "api", "go1*.txt"))
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	models := map[string]interface{}{
-		"Packages": pkgs,
+	versions := make(map[string]int)
+	newest := 0
+	for _, apiFile := range apiFiles {
+		// go1.txt is Go 1.0, go1.N.txt Go 1.N
+		version := 0
+		if name := strings.TrimSuffix(filepath.Base(apiFile), ".txt"); name != "go1" {
+			version, err = strconv.Atoi(strings.TrimPrefix(name, "go1."))
+			if err != nil {
+				return fmt.Errorf("unexpected api file %s: %w", apiFile, err)
+			}
+		}
+		newest = max(newest, version)
+
+		data, err := os.ReadFile(apiFile)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			// e.g. "pkg bytes, func ContainsFunc([]uint8, func(int32) bool) bool #54386"
+			// or "pkg syscall (linux-386), const AF_ALG = 38"
+			rest, found := strings.CutPrefix(line, "pkg ")
+			if !found {
+				continue
+			}
+			pkg, _, _ := strings.Cut(rest, ",")
+			pkg, _, _ = strings.Cut(pkg, " ")
+			if since, ok := versions[pkg]; !ok || version < since {
+				versions[pkg] = version
+			}
+		}
+	}
+
+	for pkg, version := range firstReleases {
+		versions[pkg] = version
+	}
+
+	var pkgVersions []packageVersion
+	for _, pkg := range pkgs {
+		pkgVersions = append(pkgVersions, packageVersion{pkg, versions[pkg]})
+		delete(versions, pkg)
+	}
+	// packages of the api files which are not loaded, e.g. because they are newer than the toolchain
+	for pkg, version := range versions {
+		if !strings.Contains(pkg, "internal") && !strings.Contains(pkg, "vendor") {
+			pkgVersions = append(pkgVersions, packageVersion{pkg, version})
+		}
+	}
+	slices.SortFunc(pkgVersions, func(a, b packageVersion) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return writeTemplate(versionsOutputFile, versionsTemplate, map[string]interface{}{
+		"Packages": pkgVersions,
+		"Newest":   newest,
 		"Version":  runtime.Version(),
+	})
+}
+
+func writeTemplate(path, text string, models map[string]interface{}) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
 	}
+	defer file.Close()
 
-	tlt, err := template.New("std-packages").Parse(stdTemplate)
+	tlt, err := template.New(filepath.Base(path)).Parse(text)
 	if err != nil {
 		return err
 	}
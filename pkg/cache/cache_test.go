@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "gci")
+	c, err := Open(dir, []byte("key"))
+	require.NoError(t, err)
+
+	content := []byte("package main\n")
+	assert.False(t, c.Has(content))
+	require.NoError(t, c.Add(content))
+	assert.True(t, c.Has(content))
+	// adding the same content twice is fine
+	require.NoError(t, c.Add(content))
+
+	assert.False(t, c.Has([]byte("package other\n")))
+	// the parts are not simply concatenated
+	assert.False(t, c.Has([]byte("package "), []byte("main\n")))
+
+	other, err := Open(dir, []byte("other key"))
+	require.NoError(t, err)
+	assert.False(t, other.Has(content))
+
+	reopened, err := Open(dir, []byte("key"))
+	require.NoError(t, err)
+	assert.True(t, reopened.Has(content))
+
+	require.NoError(t, Clean(dir))
+	_, err = os.Stat(dir)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestCacheConcurrentAdd(t *testing.T) {
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every goroutine uses its own handle, like parallel runs of gci
+			c, err := Open(dir, []byte("key"))
+			if err == nil {
+				err = c.Add([]byte("content"))
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	c, err := Open(dir, []byte("key"))
+	require.NoError(t, err)
+	assert.True(t, c.Has([]byte("content")))
+}
@@ -1,21 +1,29 @@
 package config
 
 import (
+	"fmt"
+	"go/token"
+	"slices"
 	"sort"
+	"strings"
 
 	"go.yaml.in/yaml/v3"
 
+	"github.com/daixiang0/gci/pkg/cache"
+	"github.com/daixiang0/gci/pkg/parse"
+	"github.com/daixiang0/gci/pkg/rules"
 	"github.com/daixiang0/gci/pkg/section"
 )
 
 var defaultOrder = map[string]int{
-	section.StandardType:    0,
-	section.DefaultType:     1,
-	section.CustomType:      2,
-	section.BlankType:       3,
-	section.DotType:         4,
-	section.AliasType:       5,
-	section.LocalModuleType: 6,
+	section.CgoType:         0,
+	section.StandardType:    1,
+	section.DefaultType:     2,
+	section.CustomType:      3,
+	section.BlankType:       4,
+	section.DotType:         5,
+	section.AliasType:       6,
+	section.LocalModuleType: 7,
 }
 
 type BoolConfig struct {
@@ -26,18 +34,102 @@ type BoolConfig struct {
 	SkipVendor       bool `yaml:"skipVendor"`
 	CustomOrder      bool `yaml:"customOrder"`
 	NoLexOrder       bool `yaml:"noLexOrder"`
+	// FormatEmbedded also formats the Go files embedded in Markdown and txtar files
+	FormatEmbedded bool `yaml:"formatEmbedded"`
+	// NoMerge formats every import declaration on its own instead of merging them into one
+	NoMerge bool `yaml:"noMerge"`
+	// Dedupe removes duplicate imports and aliases that equal the package name
+	Dedupe bool `yaml:"dedupe"`
+}
+
+// LineEndings defines which line endings the formatted files use.
+type LineEndings string
+
+const (
+	// LineEndingsPreserve keeps the line endings the file used before
+	LineEndingsPreserve LineEndings = "preserve"
+	LineEndingsLF       LineEndings = "lf"
+	LineEndingsCRLF     LineEndings = "crlf"
+)
+
+func ParseLineEndings(in string) (LineEndings, error) {
+	switch l := LineEndings(in); l {
+	case "":
+		return LineEndingsPreserve, nil
+	case LineEndingsPreserve, LineEndingsLF, LineEndingsCRLF:
+		return l, nil
+	default:
+		return "", fmt.Errorf("invalid line endings %q, must be one of %s, %s or %s", in, LineEndingsPreserve, LineEndingsLF, LineEndingsCRLF)
+	}
+}
+
+// GeneratedFiles defines how SkipGenerated detects generated files.
+type GeneratedFiles string
+
+const (
+	// GeneratedFilesStrict only skips files with a // Code generated ... DO NOT EDIT. comment before the package clause
+	GeneratedFilesStrict GeneratedFiles = "strict"
+	// GeneratedFilesLax skips files that mention a marker like "do not edit" anywhere, the default
+	GeneratedFilesLax GeneratedFiles = "lax"
+)
+
+func ParseGeneratedFiles(in string) (GeneratedFiles, error) {
+	switch g := GeneratedFiles(in); g {
+	case "":
+		return GeneratedFilesLax, nil
+	case GeneratedFilesStrict, GeneratedFilesLax:
+		return g, nil
+	default:
+		return "", fmt.Errorf("invalid generated files detection %q, must be %s or %s", in, GeneratedFilesStrict, GeneratedFilesLax)
+	}
 }
 
 type Config struct {
 	BoolConfig
 	Sections          section.SectionList
 	SectionSeparators section.SectionList
+	LineEndings       LineEndings
+	// GeneratedFiles defines which files SkipGenerated skips
+	GeneratedFiles GeneratedFiles
+	// Jobs limits how many files are processed at the same time, values below 1 use GOMAXPROCS
+	Jobs int
+	// KeepGoing processes all files even if some of them fail and reports every failure at the end
+	KeepGoing bool
+	// Cache skips files which were already formatted in a previous run, it is disabled if nil
+	Cache *cache.Cache
+	// NoStdin does not read Go source from STDIN, e.g. because STDIN lists the files to process
+	NoStdin bool
+	// StdinFilename is the path of the file whose content is read from STDIN
+	StdinFilename string
+	// Aliases maps import paths to the alias they must be imported with
+	Aliases map[string]string
+	// Rules ban imports, their violations are reported by the list command
+	Rules []rules.Rule
+	// Sort orders the imports within the sections, unless SectionSort sets another mode for the section
+	Sort parse.SortMode
+	// SectionSort maps the names of sections to the order of their imports, like prefix(github.com/org) to natural
+	SectionSort map[string]parse.SortMode
+	// SubgroupByModule lists the names of the sections whose imports are grouped by the module they belong to
+	SubgroupByModule []string
 }
 
 type YamlConfig struct {
 	Cfg                     BoolConfig `yaml:",inline"`
 	SectionStrings          []string   `yaml:"sections"`
 	SectionSeparatorStrings []string   `yaml:"sectionseparators"`
+	LineEndings             string     `yaml:"lineEndings"`
+	// GeneratedFiles is strict or lax, see GeneratedFilesStrict and GeneratedFilesLax
+	GeneratedFiles string `yaml:"generatedFiles"`
+	// Aliases maps import paths to their canonical alias, e.g. k8s.io/apimachinery/pkg/apis/meta/v1: metav1
+	Aliases map[string]string `yaml:"aliases"`
+	// Rules ban dot imports, blank imports or packages like io/ioutil
+	Rules []rules.Rule `yaml:"rules"`
+	// Sort is the order of the imports within the sections: lexical, natural, case-insensitive or by-alias
+	Sort string `yaml:"sort"`
+	// SectionSort overrides Sort for single sections, e.g. prefix(github.com/org): natural
+	SectionSort map[string]string `yaml:"sectionSort"`
+	// SubgroupByModule lists sections whose imports are separated by module, e.g. default
+	SubgroupByModule []string `yaml:"subgroupByModule"`
 
 	// Since history issue, Golangci-lint needs Analyzer to run and GCI add an Analyzer layer to integrate.
 	// The ModPath param is only from analyzer.go, no need to set it in all other places.
@@ -79,7 +171,124 @@ func (g YamlConfig) Parse() (*Config, error) {
 		sectionSeparators = section.DefaultSectionSeparators()
 	}
 
-	return &Config{g.Cfg, sections, sectionSeparators}, nil
+	lineEndings, err := ParseLineEndings(g.LineEndings)
+	if err != nil {
+		return nil, err
+	}
+
+	generatedFiles, err := ParseGeneratedFiles(g.GeneratedFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	for importPath, alias := range g.Aliases {
+		if !token.IsIdentifier(alias) || alias == "_" {
+			return nil, fmt.Errorf("invalid alias %q of %s, must be an identifier", alias, importPath)
+		}
+	}
+
+	importRules := rules.WithDefaults(g.Rules)
+	for _, r := range importRules {
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	sortMode, err := parse.ParseSortMode(g.Sort)
+	if err != nil {
+		return nil, err
+	}
+	sectionSort, err := ParseSectionSort(sections, g.SectionSort)
+	if err != nil {
+		return nil, err
+	}
+	subgroupByModule, err := ParseSubgroupByModule(sections, g.SubgroupByModule)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		BoolConfig:        g.Cfg,
+		Sections:          sections,
+		SectionSeparators: sectionSeparators,
+		LineEndings:       lineEndings,
+		GeneratedFiles:    generatedFiles,
+		Aliases:           g.Aliases,
+		Rules:             importRules,
+		Sort:              sortMode,
+		SectionSort:       sectionSort,
+		SubgroupByModule:  subgroupByModule,
+	}, nil
+}
+
+// ParseSectionSort parses the sort modes of sections, which must be part of sections. The names of the sections are
+// normalized, so Prefix(github.com/org) sets the mode of prefix(github.com/org).
+func ParseSectionSort(sections section.SectionList, modes map[string]string) (map[string]parse.SortMode, error) {
+	if len(modes) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]parse.SortMode, len(modes))
+	for name, mode := range modes {
+		s, err := sectionName(sections, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sort mode of section %s: %w", name, err)
+		}
+		if out[s], err = parse.ParseSortMode(mode); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// ParseSubgroupByModule parses the names of the sections whose imports are grouped by module, which must be part of
+// sections. It returns the normalized names in order.
+func ParseSubgroupByModule(sections section.SectionList, names []string) ([]string, error) {
+	var out []string
+	for _, name := range names {
+		s, err := sectionName(sections, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid section %s to group by module: %w", name, err)
+		}
+		out = append(out, s)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+// sectionName returns the normalized name of the section, which must be one of sections.
+func sectionName(sections section.SectionList, name string) (string, error) {
+	parsed, err := section.Parse([]string{name})
+	if err != nil {
+		return "", err
+	}
+	if len(parsed) == 0 || !slices.Contains(sections.String(), parsed[0].String()) {
+		return "", fmt.Errorf("it is not one of the sections %s", strings.Join(sections.String(), ","))
+	}
+	return parsed[0].String(), nil
+}
+
+// SortMode returns the order of the imports within section s.
+func (c Config) SortMode(s section.Section) parse.SortMode {
+	if mode, ok := c.SectionSort[s.String()]; ok {
+		return mode
+	}
+	return c.Sort
+}
+
+// SubgroupsByModule reports whether the imports of section s are separated by blank lines into groups of the same
+// module.
+func (c Config) SubgroupsByModule(s section.Section) bool {
+	return slices.Contains(c.SubgroupByModule, s.String())
+}
+
+// SortsLexically reports whether the imports of every section are sorted by path, which gofmt keeps.
+func (c Config) SortsLexically() bool {
+	for _, s := range c.Sections {
+		if mode := c.SortMode(s); mode != "" && mode != parse.SortLexical {
+			return false
+		}
+	}
+	return true
 }
 
 func ParseConfig(in string) (*Config, error) {
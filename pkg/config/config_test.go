@@ -5,6 +5,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/daixiang0/gci/pkg/parse"
+	"github.com/daixiang0/gci/pkg/rules"
 	"github.com/daixiang0/gci/pkg/section"
 )
 
@@ -42,3 +44,48 @@ func TestParseNoLexOrder(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, section.SectionList{section.Default{}, section.Custom{Prefix: "github/daixiang0/gci"}, section.Custom{Prefix: "github/daixiang0/gai"}}, gciCfg.Sections)
 }
+
+func TestParseRules(t *testing.T) {
+	gciCfg, err := ParseConfig(`
+rules:
+  - type: package
+    path: io/ioutil
+    message: io/ioutil is deprecated
+    replacement: os
+  - type: dot
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, []rules.Rule{
+		{Kind: rules.KindPackage, Path: "io/ioutil", Message: "io/ioutil is deprecated", Replacement: "os"},
+		{Kind: rules.KindDot, Message: "dot imports are only allowed in tests"},
+	}, gciCfg.Rules)
+
+	_, err = ParseConfig("rules:\n  - type: package\n")
+	assert.Error(t, err)
+}
+
+func TestParseSort(t *testing.T) {
+	gciCfg, err := ParseConfig(`
+sections:
+  - standard
+  - default
+  - prefix(github.com/daixiang0)
+sort: case-insensitive
+sectionSort:
+  Prefix(github.com/daixiang0): natural
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, parse.SortCaseInsensitive, gciCfg.SortMode(section.Default{}))
+	assert.Equal(t, parse.SortNatural, gciCfg.SortMode(section.Custom{Prefix: "github.com/daixiang0"}))
+	assert.False(t, gciCfg.SortsLexically())
+
+	gciCfg, err = ParseConfig("sections:\n  - standard\n")
+	assert.NoError(t, err)
+	assert.Equal(t, parse.SortLexical, gciCfg.SortMode(section.Standard{}))
+	assert.True(t, gciCfg.SortsLexically())
+
+	_, err = ParseConfig("sort: numeric\n")
+	assert.Error(t, err)
+	_, err = ParseConfig("sectionSort:\n  prefix(github.com/daixiang0): natural\n")
+	assert.Error(t, err)
+}
@@ -1,7 +1,9 @@
 package format
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/daixiang0/gci/pkg/config"
 	"github.com/daixiang0/gci/pkg/log"
@@ -12,35 +14,62 @@ import (
 
 type Block struct {
 	Start, End int
+	// Path is the import path of the block
+	Path string
 }
 
 type resultMap map[string][]*Block
 
+// Format groups the imports by the section they belong to, sorted by the sort mode of the section.
 func Format(data []*parse.GciImports, cfg *config.Config) (resultMap, error) {
-	result := make(resultMap, len(cfg.Sections))
+	matched := make(map[string][]*parse.GciImports, len(cfg.Sections))
 	for _, d := range data {
-		// determine match specificity for every available section
-		var bestSection section.Section
-		var bestSectionSpecificity specificity.MatchSpecificity = specificity.MisMatch{}
-		for _, section := range cfg.Sections {
-			sectionSpecificity := section.MatchSpecificity(d)
-			if sectionSpecificity.IsMoreSpecific(specificity.MisMatch{}) && sectionSpecificity.Equal(bestSectionSpecificity) {
+		bestSection, err := MatchSection(d, cfg.Sections)
+		if err != nil {
+			if errors.Is(err, section.EqualSpecificityMatchError{}) {
 				// specificity is identical
-				// return nil, section.EqualSpecificityMatchError{}
 				return nil, nil
 			}
-			if sectionSpecificity.IsMoreSpecific(bestSectionSpecificity) {
-				// better match found
-				bestSectionSpecificity = sectionSpecificity
-				bestSection = section
-			}
-		}
-		if bestSection == nil {
-			return nil, section.NoMatchingSectionForImportError{Imports: d}
+			return nil, err
 		}
 		log.L().Debug(fmt.Sprintf("Matched import %v to section %s", d, bestSection))
-		result[bestSection.String()] = append(result[bestSection.String()], &Block{d.Start, d.End})
+		matched[bestSection.String()] = append(matched[bestSection.String()], d)
 	}
 
+	result := make(resultMap, len(cfg.Sections))
+	for _, s := range cfg.Sections {
+		imports := matched[s.String()]
+		// sections may be listed twice
+		delete(matched, s.String())
+		mode := cfg.SortMode(s)
+		sort.SliceStable(imports, func(i, j int) bool {
+			return mode.Compare(imports[i], imports[j]) < 0
+		})
+		for _, d := range imports {
+			result[s.String()] = append(result[s.String()], &Block{Start: d.Start, End: d.End, Path: d.Path})
+		}
+	}
 	return result, nil
 }
+
+// MatchSection returns the section of the list that matches the import best.
+func MatchSection(d *parse.GciImports, sections section.SectionList) (section.Section, error) {
+	// determine match specificity for every available section
+	var bestSection section.Section
+	var bestSectionSpecificity specificity.MatchSpecificity = specificity.MisMatch{}
+	for _, s := range sections {
+		sectionSpecificity := s.MatchSpecificity(d)
+		if sectionSpecificity.IsMoreSpecific(specificity.MisMatch{}) && sectionSpecificity.Equal(bestSectionSpecificity) {
+			return nil, section.EqualSpecificityMatchError{Imports: d, SectionA: bestSection, SectionB: s}
+		}
+		if sectionSpecificity.IsMoreSpecific(bestSectionSpecificity) {
+			// better match found
+			bestSectionSpecificity = sectionSpecificity
+			bestSection = s
+		}
+	}
+	if bestSection == nil {
+		return nil, section.NoMatchingSectionForImportError{Imports: d}
+	}
+	return bestSection, nil
+}
@@ -0,0 +1,79 @@
+package gci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daixiang0/gci/pkg/cache"
+	"github.com/daixiang0/gci/pkg/config"
+)
+
+type memoryFile struct {
+	path    string
+	content string
+}
+
+func (f memoryFile) Load() ([]byte, error) {
+	return []byte(f.content), nil
+}
+
+func (f memoryFile) Path() string {
+	return f.path
+}
+
+func TestCacheKey(t *testing.T) {
+	cfg, err := config.ParseConfig("sections:\n  - standard\n  - default\n")
+	require.NoError(t, err)
+	key := CacheKey(*cfg, "1.0.0")
+
+	assert.Equal(t, key, CacheKey(*cfg, "1.0.0"))
+	assert.NotEqual(t, key, CacheKey(*cfg, "1.0.1"))
+
+	runtimeOnly := *cfg
+	runtimeOnly.Jobs = 3
+	runtimeOnly.KeepGoing = true
+	runtimeOnly.Debug = true
+	assert.Equal(t, key, CacheKey(runtimeOnly, "1.0.0"))
+
+	other, err := config.ParseConfig("sections:\n  - standard\n  - default\n  - prefix(github.com/daixiang0)\n")
+	require.NoError(t, err)
+	assert.NotEqual(t, key, CacheKey(*other, "1.0.0"))
+
+	other, err = config.ParseConfig("sections:\n  - standard\n  - default\nskipGenerated: true\n")
+	require.NoError(t, err)
+	assert.NotEqual(t, key, CacheKey(*other, "1.0.0"))
+
+	other, err = config.ParseConfig("sections:\n  - standard\n  - default\naliases:\n  k8s.io/api/core/v1: corev1\n")
+	require.NoError(t, err)
+	assert.NotEqual(t, key, CacheKey(*other, "1.0.0"))
+}
+
+func TestLoadFormatGoFileUsesCache(t *testing.T) {
+	cfg, err := config.ParseConfig("sections:\n  - standard\n  - default\n")
+	require.NoError(t, err)
+	cfg.Cache, err = cache.Open(t.TempDir(), CacheKey(*cfg, "test"))
+	require.NoError(t, err)
+
+	formatted := memoryFile{"a.go", "package a\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n"}
+	src, dist, err := LoadFormatGoFile(formatted, *cfg)
+	require.NoError(t, err)
+	assert.Equal(t, src, dist)
+	assert.True(t, cfg.Cache.Has([]byte("go"), src))
+
+	// files that need changes are never cached
+	unformatted := memoryFile{"b.go", "package b\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n"}
+	src, dist, err = LoadFormatGoFile(unformatted, *cfg)
+	require.NoError(t, err)
+	assert.NotEqual(t, src, dist)
+	assert.False(t, cfg.Cache.Has([]byte("go"), src))
+
+	// cached content is returned unchanged without parsing it
+	invalid := memoryFile{"c.go", "not go"}
+	require.NoError(t, cfg.Cache.Add([]byte("go"), []byte(invalid.content)))
+	src, dist, err = LoadFormatGoFile(invalid, *cfg)
+	require.NoError(t, err)
+	assert.Equal(t, invalid.content, string(dist))
+	assert.Equal(t, src, dist)
+}
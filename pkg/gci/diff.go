@@ -0,0 +1,260 @@
+package gci
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+
+	"github.com/daixiang0/gci/pkg/config"
+	"github.com/daixiang0/gci/pkg/format"
+	"github.com/daixiang0/gci/pkg/parse"
+)
+
+// ColorMode decides whether diffs are colored.
+type ColorMode string
+
+const (
+	ColorAuto   ColorMode = "auto"
+	ColorAlways ColorMode = "always"
+	ColorNever  ColorMode = "never"
+)
+
+func ParseColorMode(in string) (ColorMode, error) {
+	switch mode := ColorMode(strings.ToLower(in)); mode {
+	case ColorAuto, ColorAlways, ColorNever:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid color mode %q, must be one of %s, %s or %s", in, ColorAuto, ColorAlways, ColorNever)
+	}
+}
+
+// Enabled reports whether the output written to out should be colored.
+// In auto mode colors are only used for terminals and are disabled by a non-empty NO_COLOR, see https://no-color.org.
+func (m ColorMode) Enabled(out *os.File) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	stat, err := out.Stat()
+	if err != nil {
+		return false
+	}
+	return (stat.Mode() & os.ModeCharDevice) != 0
+}
+
+// DiffOptions controls how the changes of a file are rendered.
+type DiffOptions struct {
+	Color bool
+	// SideBySide shows the original and the formatted import block next to each other instead of a unified diff.
+	SideBySide bool
+}
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiCyan  = "\x1b[36m"
+)
+
+func colorize(enabled bool, code, s string) string {
+	if !enabled || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func renderDiff(filePath string, unmodifiedFile, formattedFile []byte, cfg config.Config, opts DiffOptions) (string, error) {
+	var diff string
+	if opts.SideBySide {
+		var err error
+		diff, err = renderSideBySide(filePath, unmodifiedFile, formattedFile, cfg, opts.Color)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		diff = renderUnified(filePath, unmodifiedFile, formattedFile, opts.Color)
+	}
+	if diff == "" {
+		return "", nil
+	}
+
+	// patch tools skip the lines in front of a diff, so the renamed and removed imports can be listed there
+	var notes strings.Builder
+	for _, change := range importChanges(unmodifiedFile, filePath, cfg) {
+		notes.WriteString(colorize(opts.Color, ansiCyan, fmt.Sprintf("%s: %s", filePath, change)))
+		notes.WriteByte('\n')
+	}
+	return notes.String() + diff, nil
+}
+
+// importChanges describes the imports that are renamed by the aliases of cfg and removed by the dedupe mode.
+func importChanges(src []byte, filePath string, cfg config.Config) []string {
+	src, _ = normalize(src)
+	src, changes, conflicts, err := parse.RewriteAliases(src, filePath, cfg.Aliases)
+	if err != nil {
+		return nil
+	}
+	changes = append(changes, conflicts...)
+	if !cfg.Dedupe {
+		return changes
+	}
+	if !cfg.NoMerge {
+		src, err = parse.MergeImportDecls(src, filePath)
+		if err != nil {
+			return changes
+		}
+	}
+	_, removed, err := parse.DedupeImports(src, filePath)
+	if err != nil {
+		return changes
+	}
+	return append(changes, removed...)
+}
+
+func renderUnified(filePath string, unmodifiedFile, formattedFile []byte, color bool) string {
+	fileURI := span.URIFromPath(filePath)
+	edits := myers.ComputeEdits(fileURI, string(unmodifiedFile), string(formattedFile))
+	unifiedEdits := fmt.Sprint(gotextdiff.ToUnified(filePath, filePath, string(unmodifiedFile), edits))
+	if !color || unifiedEdits == "" {
+		return unifiedEdits
+	}
+
+	lines := strings.SplitAfter(unifiedEdits, "\n")
+	var b strings.Builder
+	for i, line := range lines {
+		content := strings.TrimSuffix(line, "\n")
+		switch {
+		// the first two lines are always the file headers
+		case i < 2:
+			b.WriteString(colorize(true, ansiBold, content))
+		case strings.HasPrefix(content, "@@"):
+			b.WriteString(colorize(true, ansiCyan, content))
+		case strings.HasPrefix(content, "-"):
+			b.WriteString(colorize(true, ansiRed, content))
+		case strings.HasPrefix(content, "+"):
+			b.WriteString(colorize(true, ansiGreen, content))
+		default:
+			b.WriteString(content)
+		}
+		if len(content) != len(line) {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+const sideBySideTabWidth = 4
+
+type importBlockLine struct {
+	text, label string
+}
+
+// importBlock returns the lines of the import declarations of the file.
+// Every line that holds an import path is labeled with the section the import belongs to. It is empty if the file
+// has no imports.
+func importBlock(src []byte, filePath string, cfg config.Config) ([]importBlockLine, error) {
+	imports, headEnd, tailStart, _, _, err := parse.ParseFile(src, filePath)
+	if errors.Is(err, parse.NoImportError{}) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sections, err := cfg.Sections.ForFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// tailStart and the end of an import include the following linebreak
+	block := strings.Split(strings.TrimSuffix(string(src[headEnd:tailStart]), "\n"), "\n")
+	lines := make([]importBlockLine, len(block))
+	for i, text := range block {
+		lines[i].text = strings.ReplaceAll(strings.TrimRight(text, "\r"), "\t", strings.Repeat(" ", sideBySideTabWidth))
+	}
+	for _, imp := range imports {
+		s, err := format.MatchSection(imp, sections)
+		if err != nil {
+			continue
+		}
+		lines[strings.Count(string(src[headEnd:imp.End-1]), "\n")].label = s.String()
+	}
+	return lines, nil
+}
+
+func renderSideBySide(filePath string, unmodifiedFile, formattedFile []byte, cfg config.Config, color bool) (string, error) {
+	if string(unmodifiedFile) == string(formattedFile) {
+		return "", nil
+	}
+
+	left, err := importBlock(unmodifiedFile, filePath, cfg)
+	if err != nil {
+		return "", err
+	}
+	right, err := importBlock(formattedFile, filePath, cfg)
+	if err != nil {
+		return "", err
+	}
+	if left == nil && right == nil {
+		// the change is outside of the imports, e.g. of the line endings
+		return renderUnified(filePath, unmodifiedFile, formattedFile, color), nil
+	}
+
+	const leftTitle, rightTitle = "original", "formatted"
+	leftWidth, rightWidth := utf8.RuneCountInString(leftTitle), utf8.RuneCountInString(rightTitle)
+	for _, l := range left {
+		leftWidth = max(leftWidth, utf8.RuneCountInString(l.text))
+	}
+	for _, l := range right {
+		rightWidth = max(rightWidth, utf8.RuneCountInString(l.text))
+	}
+	cell := func(text string, width int, code string) string {
+		return colorize(color && code != "", code, text) + strings.Repeat(" ", width-utf8.RuneCountInString(text))
+	}
+
+	var b strings.Builder
+	b.WriteString(colorize(color, ansiBold, filePath+":"))
+	b.WriteByte('\n')
+	b.WriteString(cell(leftTitle, leftWidth, ansiBold) + "   " + colorize(color, ansiBold, rightTitle))
+	b.WriteByte('\n')
+	for i := 0; i < max(len(left), len(right)); i++ {
+		var l, r importBlockLine
+		marker := " | "
+		switch {
+		case i >= len(right):
+			l, marker = left[i], " < "
+		case i >= len(left):
+			r, marker = right[i], " > "
+		default:
+			l, r = left[i], right[i]
+			if l.text == r.text {
+				marker = "   "
+			}
+		}
+		leftCode, rightCode := ansiRed, ansiGreen
+		if marker == "   " {
+			leftCode, rightCode = "", ""
+		}
+
+		line := cell(l.text, leftWidth, leftCode) + marker
+		if r.label != "" {
+			line += cell(r.text, rightWidth, rightCode) + "  " + colorize(color, ansiCyan, r.label)
+		} else {
+			line += colorize(color && rightCode != "", rightCode, r.text)
+		}
+		b.WriteString(strings.TrimRight(line, " "))
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
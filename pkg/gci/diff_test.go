@@ -0,0 +1,112 @@
+package gci
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daixiang0/gci/pkg/config"
+)
+
+var diffTestInput = `package main
+
+import (
+	"github.com/daixiang0/gci"
+	"fmt"
+)
+`
+
+func TestRenderDiffSideBySide(t *testing.T) {
+	cfg, err := config.ParseConfig(commonConfig)
+	require.NoError(t, err)
+
+	_, formatted, err := LoadFormat([]byte(diffTestInput), "main.go", *cfg)
+	require.NoError(t, err)
+
+	got, err := renderDiff("main.go", []byte(diffTestInput), formatted, *cfg, DiffOptions{SideBySide: true})
+	require.NoError(t, err)
+	assert.Equal(t, `main.go:
+original                         formatted
+import (                         import (
+    "github.com/daixiang0/gci" |     "fmt"                       standard
+    "fmt"                      |
+)                              |     "github.com/daixiang0/gci"  prefix(github.com/daixiang0)
+                               > )
+`, got)
+
+	got, err = renderDiff("main.go", formatted, formatted, *cfg, DiffOptions{SideBySide: true})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestRenderDiffSideBySideWithoutImports(t *testing.T) {
+	cfg, err := config.ParseConfig(commonConfig)
+	require.NoError(t, err)
+
+	// only the line endings change, there is no import block to show
+	got, err := renderDiff("main.go", []byte("package main\r\n"), []byte("package main\n"), *cfg, DiffOptions{SideBySide: true})
+	require.NoError(t, err)
+	assert.Equal(t, renderUnified("main.go", []byte("package main\r\n"), []byte("package main\n"), false), got)
+	assert.NotEmpty(t, got)
+}
+
+func TestRenderDiffListsDedupedImports(t *testing.T) {
+	cfg, err := config.ParseConfig(commonConfig + "dedupe: true\n")
+	require.NoError(t, err)
+
+	in := []byte(`package main
+
+import (
+	"fmt"
+	yaml "gopkg.in/yaml.v3"
+	"fmt"
+)
+`)
+	_, formatted, err := LoadFormat(in, "main.go", *cfg)
+	require.NoError(t, err)
+
+	got, err := renderDiff("main.go", in, formatted, *cfg, DiffOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, got, `main.go: removed redundant alias yaml of "gopkg.in/yaml.v3"
+main.go: removed duplicate import "fmt"
+--- main.go
+`)
+}
+
+func TestRenderDiffColor(t *testing.T) {
+	cfg, err := config.ParseConfig(commonConfig)
+	require.NoError(t, err)
+
+	_, formatted, err := LoadFormat([]byte(diffTestInput), "main.go", *cfg)
+	require.NoError(t, err)
+
+	plain, err := renderDiff("main.go", []byte(diffTestInput), formatted, *cfg, DiffOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, plain, "\x1b[")
+
+	colored, err := renderDiff("main.go", []byte(diffTestInput), formatted, *cfg, DiffOptions{Color: true})
+	require.NoError(t, err)
+	assert.Contains(t, colored, ansiBold+"--- main.go"+ansiReset+"\n")
+	assert.Contains(t, colored, ansiRed+"-\t\"github.com/daixiang0/gci\""+ansiReset+"\n")
+	assert.Contains(t, colored, ansiGreen+"+"+ansiReset+"\n")
+}
+
+func TestColorModeEnabled(t *testing.T) {
+	_, err := ParseColorMode("sometimes")
+	assert.Error(t, err)
+
+	mode, err := ParseColorMode("ALWAYS")
+	require.NoError(t, err)
+	assert.Equal(t, ColorAlways, mode)
+
+	out, err := os.CreateTemp(t.TempDir(), "out")
+	require.NoError(t, err)
+	defer out.Close()
+
+	assert.True(t, ColorAlways.Enabled(out))
+	assert.False(t, ColorNever.Enabled(out))
+	// a regular file is not a terminal
+	assert.False(t, ColorAuto.Enabled(out))
+}
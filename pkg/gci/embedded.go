@@ -0,0 +1,163 @@
+package gci
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/txtar"
+
+	"github.com/daixiang0/gci/pkg/config"
+	"github.com/daixiang0/gci/pkg/log"
+)
+
+// snippet is a Go file embedded in another file, like a fenced code block in Markdown.
+type snippet struct {
+	// name identifies the snippet in messages
+	name string
+	// start and end are the offsets of the Go source in the embedding file
+	start, end int
+	// indent is stripped from every line of the snippet before formatting and added back afterwards
+	indent string
+}
+
+// LoadFormatEmbedded formats every Go file embedded in src and splices the results back in place.
+// Everything that is not Go source stays untouched, and snippets which can not be parsed are skipped.
+func LoadFormatEmbedded(in []byte, path string, cfg config.Config) (src, dist []byte, err error) {
+	src = in
+
+	var snippets []snippet
+	if strings.ToLower(filepath.Ext(path)) == ".txtar" {
+		snippets = txtarSnippets(src, path)
+	} else {
+		snippets = markdownSnippets(src, path)
+	}
+
+	var last int
+	for _, s := range snippets {
+		code := []byte(src[s.start:s.end])
+		if s.indent != "" {
+			code = []byte(strings.ReplaceAll("\n"+string(code), "\n"+s.indent, "\n")[1:])
+		}
+
+		_, formatted, err := LoadFormat(code, s.name, cfg)
+		if err != nil {
+			log.L().Warn(fmt.Sprintf("Skipping Go snippet %s: %v", s.name, err))
+			continue
+		}
+		if bytes.Equal(code, formatted) {
+			continue
+		}
+		if s.indent != "" {
+			formatted = indentLines(formatted, s.indent)
+		}
+
+		dist = append(dist, src[last:s.start]...)
+		dist = append(dist, formatted...)
+		last = s.end
+	}
+	if dist == nil {
+		return src, src, nil
+	}
+	dist = append(dist, src[last:]...)
+	return src, dist, nil
+}
+
+func indentLines(in []byte, indent string) []byte {
+	lines := bytes.SplitAfter(in, []byte{'\n'})
+	var out []byte
+	for _, line := range lines {
+		if len(bytes.TrimSpace(line)) > 0 {
+			out = append(out, indent...)
+		}
+		out = append(out, line...)
+	}
+	return out
+}
+
+// markdownSnippets finds the fenced code blocks with the info string go or golang.
+// Blocks which are not closed are ignored.
+func markdownSnippets(src []byte, path string) []snippet {
+	var (
+		snippets []snippet
+		open     *snippet
+		fence    string
+		offset   int
+	)
+	for lineNo, line := range strings.SplitAfter(string(src), "\n") {
+		lineStart := offset
+		offset += len(line)
+
+		trimmed := strings.TrimRight(line, "\r\n")
+		content := strings.TrimLeft(trimmed, " \t")
+		indent := trimmed[:len(trimmed)-len(content)]
+
+		if open != nil {
+			// the closing fence uses the same character at least as often as the opening one
+			if strings.HasPrefix(content, fence) && strings.Trim(content, fence[:1]+" \t") == "" {
+				open.end = lineStart
+				snippets = append(snippets, *open)
+				open = nil
+			}
+			continue
+		}
+
+		char := ""
+		switch {
+		case strings.HasPrefix(content, "```"):
+			char = "`"
+		case strings.HasPrefix(content, "~~~"):
+			char = "~"
+		default:
+			continue
+		}
+		info := strings.TrimLeft(content, char)
+		fence = content[:len(content)-len(info)]
+		if lang := strings.Fields(info); len(lang) > 0 && (lang[0] == "go" || lang[0] == "golang") {
+			open = &snippet{
+				name:   fmt.Sprintf("%s:%d", path, lineNo+1),
+				start:  offset,
+				indent: indent,
+			}
+		} else {
+			// skip the content of blocks in other languages
+			open = &snippet{}
+		}
+	}
+
+	result := snippets[:0]
+	for _, s := range snippets {
+		if s.name != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// txtarSnippets finds the files in a txtar archive whose names end in .go.
+func txtarSnippets(src []byte, path string) []snippet {
+	archive := txtar.Parse(src)
+
+	var snippets []snippet
+	offset := len(archive.Comment)
+	for _, f := range archive.Files {
+		// skip the marker line of the file
+		markerEnd := bytes.IndexByte(src[offset:], '\n')
+		if markerEnd < 0 {
+			break
+		}
+		start := offset + markerEnd + 1
+		// Parse adds a missing linebreak to the last file
+		end := min(start+len(f.Data), len(src))
+		if strings.HasSuffix(f.Name, ".go") {
+			snippets = append(snippets, snippet{
+				name:  path + ":" + f.Name,
+				start: start,
+				end:   end,
+			})
+		}
+		offset = end
+	}
+	return snippets
+}
@@ -0,0 +1,159 @@
+package gci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daixiang0/gci/pkg/config"
+)
+
+func embeddedTestConfig(t *testing.T) config.Config {
+	cfg, err := config.ParseConfig(`sections:
+  - Standard
+  - Default
+`)
+	require.NoError(t, err)
+	return *cfg
+}
+
+func TestLoadFormatEmbeddedMarkdown(t *testing.T) {
+	in := "# Usage\n" +
+		"\n" +
+		"```go\n" +
+		"package main\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"github.com/daixiang0/gci\"\n" +
+		"\t\"fmt\"\n" +
+		")\n" +
+		"```\n" +
+		"\n" +
+		"```sh\n" +
+		"import ( b a )\n" +
+		"```\n" +
+		"\n" +
+		"1. In a list:\n" +
+		"   ~~~~golang\n" +
+		"   package main\n" +
+		"\n" +
+		"   import (\n" +
+		"   \t\"os\"\n" +
+		"   \t\"fmt\"\n" +
+		"   )\n" +
+		"   ~~~~\n" +
+		"\n" +
+		"```go\n" +
+		"fmt.Println(\"not a file\")\n" +
+		"```\n"
+	out := "# Usage\n" +
+		"\n" +
+		"```go\n" +
+		"package main\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"fmt\"\n" +
+		"\n" +
+		"\t\"github.com/daixiang0/gci\"\n" +
+		")\n" +
+		"```\n" +
+		"\n" +
+		"```sh\n" +
+		"import ( b a )\n" +
+		"```\n" +
+		"\n" +
+		"1. In a list:\n" +
+		"   ~~~~golang\n" +
+		"   package main\n" +
+		"\n" +
+		"   import (\n" +
+		"   \t\"fmt\"\n" +
+		"   \t\"os\"\n" +
+		"   )\n" +
+		"   ~~~~\n" +
+		"\n" +
+		"```go\n" +
+		"fmt.Println(\"not a file\")\n" +
+		"```\n"
+
+	src, dist, err := LoadFormatEmbedded([]byte(in), "README.md", embeddedTestConfig(t))
+	require.NoError(t, err)
+	assert.Equal(t, in, string(src))
+	assert.Equal(t, out, string(dist))
+}
+
+func TestLoadFormatEmbeddedMarkdownUnclosedFence(t *testing.T) {
+	in := "```go\npackage main\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n"
+
+	_, dist, err := LoadFormatEmbedded([]byte(in), "README.md", embeddedTestConfig(t))
+	require.NoError(t, err)
+	assert.Equal(t, in, string(dist))
+}
+
+func TestLoadFormatEmbeddedTxtar(t *testing.T) {
+	in := "comment\n" +
+		"-- go.mod --\n" +
+		"module example.com/m\n" +
+		"-- a.go --\n" +
+		"package a\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"os\"\n" +
+		"\t\"fmt\"\n" +
+		")\n" +
+		"-- b.txt --\n" +
+		"import (\n" +
+		"\t\"os\"\n" +
+		"\t\"fmt\"\n" +
+		")\n" +
+		"-- b.go --\n" +
+		"package b\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"os\"\n" +
+		"\t\"fmt\"\n" +
+		")"
+	out := "comment\n" +
+		"-- go.mod --\n" +
+		"module example.com/m\n" +
+		"-- a.go --\n" +
+		"package a\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"fmt\"\n" +
+		"\t\"os\"\n" +
+		")\n" +
+		"-- b.txt --\n" +
+		"import (\n" +
+		"\t\"os\"\n" +
+		"\t\"fmt\"\n" +
+		")\n" +
+		"-- b.go --\n" +
+		"package b\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"fmt\"\n" +
+		"\t\"os\"\n" +
+		")\n"
+
+	src, dist, err := LoadFormatEmbedded([]byte(in), "testdata/script.txtar", embeddedTestConfig(t))
+	require.NoError(t, err)
+	assert.Equal(t, in, string(src))
+	assert.Equal(t, out, string(dist))
+}
+
+func TestLoadFormatEmbeddedSkipsInvalidSnippets(t *testing.T) {
+	in := "-- a.go --\n" +
+		"package a\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"os\"\n" +
+		"\t\"fmt\"\n" +
+		"\n" +
+		"func {\n"
+
+	_, dist, err := LoadFormatEmbedded([]byte(in), "broken.txtar", embeddedTestConfig(t))
+	require.NoError(t, err)
+	assert.Equal(t, in, string(dist))
+}
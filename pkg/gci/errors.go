@@ -0,0 +1,72 @@
+package gci
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// FileError is the error of a single file that could not be processed.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// FileErrors returns the errors of all failed files contained in err.
+func FileErrors(err error) []*FileError {
+	var fileErrors []*FileError
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		var fileErr *FileError
+		if errors.As(err, &fileErr) {
+			fileErrors = append(fileErrors, fileErr)
+		}
+		return fileErrors
+	}
+	for _, e := range joined.Unwrap() {
+		fileErrors = append(fileErrors, FileErrors(e)...)
+	}
+	return fileErrors
+}
+
+// PrintErrorSummary writes a table of the failed files in err to w.
+// It reports whether err contained any failed files, otherwise nothing is written.
+func PrintErrorSummary(w io.Writer, err error) bool {
+	fileErrors := FileErrors(err)
+	if len(fileErrors) == 0 {
+		return false
+	}
+
+	noun := "files"
+	if len(fileErrors) == 1 {
+		noun = "file"
+	}
+	fmt.Fprintf(w, "%d %s could not be processed:\n", len(fileErrors), noun)
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "FILE\tERROR")
+	for _, e := range fileErrors {
+		path, message := e.Path, e.Err.Error()
+		if path == "" {
+			path = "-"
+		} else {
+			// parse errors already start with the path of the file
+			message = strings.TrimPrefix(message, path+":")
+		}
+		fmt.Fprintf(table, "%s\t%s\n", path, strings.ReplaceAll(message, "\n", " "))
+	}
+	table.Flush()
+	return true
+}
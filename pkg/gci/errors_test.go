@@ -0,0 +1,86 @@
+package gci
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daixiang0/gci/pkg/config"
+	"github.com/daixiang0/gci/pkg/io"
+)
+
+func writeErrorTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go": "package a\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n",
+		"b.go": "package b\nimport (\n",
+		"c.go": "broken",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestProcessFilesKeepGoing(t *testing.T) {
+	dir := writeErrorTree(t)
+	cfg, err := config.ParseConfig("")
+	require.NoError(t, err)
+	cfg.KeepGoing = true
+
+	var lock sync.Mutex
+	var processed []string
+	err = ProcessFiles(io.GoFilesInPathsGenerator([]string{dir, filepath.Join(dir, "missing")}, false), *cfg, func(filePath string, _, _ []byte) error {
+		lock.Lock()
+		defer lock.Unlock()
+		processed = append(processed, filePath)
+		return nil
+	})
+
+	assert.Equal(t, []string{filepath.Join(dir, "a.go")}, processed)
+	fileErrors := FileErrors(err)
+	require.Len(t, fileErrors, 3)
+	assert.Equal(t, filepath.Join(dir, "b.go"), fileErrors[0].Path)
+	assert.Equal(t, filepath.Join(dir, "c.go"), fileErrors[1].Path)
+	assert.Equal(t, filepath.Join(dir, "missing"), fileErrors[2].Path)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestProcessFilesFailFast(t *testing.T) {
+	dir := writeErrorTree(t)
+	cfg, err := config.ParseConfig("")
+	require.NoError(t, err)
+	cfg.Jobs = 1
+
+	err = ProcessFiles(io.GoFilesInPathsGenerator([]string{dir}, false), *cfg, func(string, []byte, []byte) error {
+		return nil
+	})
+	require.Error(t, err)
+	assert.Empty(t, FileErrors(err))
+}
+
+func TestPrintErrorSummary(t *testing.T) {
+	err := errors.Join(
+		&FileError{Path: "b.go", Err: errors.New("b.go:2:10: expected ')', found 'EOF'")},
+		&FileError{Path: "dir/c.go", Err: errors.New("permission denied")},
+	)
+
+	var out strings.Builder
+	assert.True(t, PrintErrorSummary(&out, err))
+	assert.Equal(t, "2 files could not be processed:\n"+
+		"FILE      ERROR\n"+
+		"b.go      2:10: expected ')', found 'EOF'\n"+
+		"dir/c.go  permission denied\n", out.String())
+
+	out.Reset()
+	assert.False(t, PrintErrorSummary(&out, errors.New("other")))
+	assert.False(t, PrintErrorSummary(&out, nil))
+	assert.Empty(t, out.String())
+}
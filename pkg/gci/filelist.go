@@ -0,0 +1,29 @@
+package gci
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/daixiang0/gci/pkg/config"
+	"github.com/daixiang0/gci/pkg/io"
+	"github.com/daixiang0/gci/pkg/log"
+)
+
+// FilterFileList drops the paths of a file list that do not exist or can not be formatted, with a warning for each.
+// Directories are kept and searched like paths given on the command line.
+func FilterFileList(paths []string, cfg config.Config) []string {
+	var kept []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			log.L().Warn(fmt.Sprintf("Skipping listed file: %v", err))
+		case info.IsDir(), filepath.Ext(path) == ".go", cfg.FormatEmbedded && io.IsEmbeddingFile(path):
+			kept = append(kept, path)
+		default:
+			log.L().Warn(fmt.Sprintf("Skipping listed file %s: not a Go file", path))
+		}
+	}
+	return kept
+}
@@ -0,0 +1,32 @@
+package gci
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daixiang0/gci/pkg/config"
+)
+
+func TestFilterFileList(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "README.md", "notes.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "pkg"), 0o755))
+	listed := []string{
+		filepath.Join(dir, "a.go"),
+		filepath.Join(dir, "missing.go"),
+		filepath.Join(dir, "README.md"),
+		filepath.Join(dir, "notes.txt"),
+		filepath.Join(dir, "pkg"),
+	}
+
+	assert.Equal(t, []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "pkg")}, FilterFileList(listed, config.Config{}))
+
+	embedded := config.Config{BoolConfig: config.BoolConfig{FormatEmbedded: true}}
+	assert.Equal(t, []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "README.md"), filepath.Join(dir, "pkg")}, FilterFileList(listed, embedded))
+}
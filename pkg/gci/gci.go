@@ -2,15 +2,15 @@ package gci
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	goFormat "go/format"
-	"os"
+	"io/fs"
+	"runtime"
+	"sort"
 	"sync"
 
-	"github.com/hexops/gotextdiff"
-	"github.com/hexops/gotextdiff/myers"
-	"github.com/hexops/gotextdiff/span"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/daixiang0/gci/pkg/config"
@@ -45,26 +45,45 @@ func WriteFormattedFiles(paths []string, cfg config.Config) error {
 			return nil
 		}
 		log.L().Info(fmt.Sprintf("Writing formatted File: %s", filePath))
-		return os.WriteFile(filePath, formattedFile, 0o644)
+		return io.WriteFileAtomic(filePath, formattedFile)
 	})
 }
 
+// ListUnFormattedFiles prints the files that need to be formatted, followed by the imports that break the rules of
+// the configuration. If any import breaks a rule, the error contains a *ViolationsError.
 func ListUnFormattedFiles(paths []string, cfg config.Config) error {
-	return processGoFilesInPaths(paths, cfg, func(filePath string, unmodifiedFile, formattedFile []byte) error {
-		if bytes.Equal(unmodifiedFile, formattedFile) {
-			return nil
+	count := 0
+	err := processGoFilesInPaths(paths, cfg, func(filePath string, unmodifiedFile, formattedFile []byte) error {
+		if !bytes.Equal(unmodifiedFile, formattedFile) {
+			fmt.Println(filePath)
+		}
+		violations, err := CheckImports(filePath, unmodifiedFile, cfg)
+		if err != nil {
+			return err
+		}
+		for _, v := range violations {
+			fmt.Println(v)
 		}
-		fmt.Println(filePath)
+		count += len(violations)
 		return nil
 	})
+	if count > 0 {
+		return errors.Join(err, &ViolationsError{Count: count})
+	}
+	return err
 }
 
 func DiffFormattedFiles(paths []string, cfg config.Config) error {
+	return DiffFormattedFilesWithOptions(paths, cfg, DiffOptions{})
+}
+
+func DiffFormattedFilesWithOptions(paths []string, cfg config.Config, opts DiffOptions) error {
 	return processStdInAndGoFilesInPaths(paths, cfg, func(filePath string, unmodifiedFile, formattedFile []byte) error {
-		fileURI := span.URIFromPath(filePath)
-		edits := myers.ComputeEdits(fileURI, string(unmodifiedFile), string(formattedFile))
-		unifiedEdits := gotextdiff.ToUnified(filePath, filePath, string(unmodifiedFile), edits)
-		fmt.Printf("%v", unifiedEdits)
+		diff, err := renderDiff(filePath, unmodifiedFile, formattedFile, cfg, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Print(diff)
 		return nil
 	})
 }
@@ -73,11 +92,12 @@ func DiffFormattedFilesToArray(paths []string, cfg config.Config, diffs *[]strin
 	log.InitLogger()
 	defer log.L().Sync()
 	return processStdInAndGoFilesInPaths(paths, cfg, func(filePath string, unmodifiedFile, formattedFile []byte) error {
-		fileURI := span.URIFromPath(filePath)
-		edits := myers.ComputeEdits(fileURI, string(unmodifiedFile), string(formattedFile))
-		unifiedEdits := gotextdiff.ToUnified(filePath, filePath, string(unmodifiedFile), edits)
+		diff, err := renderDiff(filePath, unmodifiedFile, formattedFile, cfg, DiffOptions{})
+		if err != nil {
+			return err
+		}
 		lock.Lock()
-		*diffs = append(*diffs, fmt.Sprint(unifiedEdits))
+		*diffs = append(*diffs, diff)
 		lock.Unlock()
 		return nil
 	})
@@ -86,38 +106,100 @@ func DiffFormattedFilesToArray(paths []string, cfg config.Config, diffs *[]strin
 type fileFormattingFunc func(filePath string, unmodifiedFile, formattedFile []byte) error
 
 func processStdInAndGoFilesInPaths(paths []string, cfg config.Config, fileFunc fileFormattingFunc) error {
-	return ProcessFiles(io.StdInGenerator.Combine(io.GoFilesInPathsGenerator(paths, cfg.SkipVendor)), cfg, fileFunc)
+	if cfg.NoStdin {
+		return ProcessFiles(filesInPathsGenerator(paths, cfg), cfg, fileFunc)
+	}
+	return ProcessFiles(io.StdInGeneratorWithPath(cfg.StdinFilename).Combine(filesInPathsGenerator(paths, cfg)), cfg, fileFunc)
 }
 
 func processGoFilesInPaths(paths []string, cfg config.Config, fileFunc fileFormattingFunc) error {
-	return ProcessFiles(io.GoFilesInPathsGenerator(paths, cfg.SkipVendor), cfg, fileFunc)
+	return ProcessFiles(filesInPathsGenerator(paths, cfg), cfg, fileFunc)
+}
+
+func filesInPathsGenerator(paths []string, cfg config.Config) io.FileGeneratorFunc {
+	if cfg.FormatEmbedded {
+		return io.GoAndEmbeddingFilesInPathsGenerator(paths, cfg.SkipVendor)
+	}
+	return io.GoFilesInPathsGenerator(paths, cfg.SkipVendor)
 }
 
+// fileResult is the outcome of formatting a single file.
+type fileResult struct {
+	path                          string
+	unmodifiedFile, formattedFile []byte
+	err                           error
+}
+
+// ProcessFiles formats the files of the generator in parallel, but passes them to fileFunc one after
+// another in the order they were found in, so the output does not depend on scheduling.
 func ProcessFiles(fileGenerator io.FileGeneratorFunc, cfg config.Config, fileFunc fileFormattingFunc) error {
-	var taskGroup errgroup.Group
-	files, err := fileGenerator()
-	if err != nil {
-		return err
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// every found file gets a place in pending, its capacity limits how many results are waiting for their turn
+	pending := make(chan chan fileResult, jobs(cfg))
+	go func() {
+		defer close(pending)
+		var workers errgroup.Group
+		workers.SetLimit(jobs(cfg))
+		defer workers.Wait()
+
+		for file, err := range fileGenerator {
+			result := make(chan fileResult, 1)
+			select {
+			case pending <- result:
+			case <-ctx.Done():
+				// stop searching for files as soon as one of them failed
+				return
+			}
+			if err != nil {
+				result <- fileResult{path: errorPath(err), err: err}
+				continue
+			}
+			// Go blocks while all workers are busy, so files are found only as fast as they are processed
+			workers.Go(func() error {
+				unmodifiedFile, formattedFile, err := LoadFormatGoFile(file, cfg)
+				result <- fileResult{file.Path(), unmodifiedFile, formattedFile, err}
+				return nil
+			})
+		}
+	}()
+
+	var errs []error
+	for result := range pending {
+		r := <-result
+		if r.err == nil {
+			r.err = fileFunc(r.path, r.unmodifiedFile, r.formattedFile)
+		}
+		if r.err == nil {
+			continue
+		}
+		if !cfg.KeepGoing {
+			cancel()
+			// wait for the files that are still being formatted
+			for range pending {
+			}
+			return r.err
+		}
+		errs = append(errs, &FileError{Path: r.path, Err: r.err})
 	}
-	for _, file := range files {
-		// run file processing in parallel
-		taskGroup.Go(processingFunc(file, cfg, fileFunc))
+	return errors.Join(errs...)
+}
+
+// errorPath returns the path an error of the file search refers to, if any.
+func errorPath(err error) string {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Path
 	}
-	return taskGroup.Wait()
+	return ""
 }
 
-func processingFunc(file io.FileObj, cfg config.Config, formattingFunc fileFormattingFunc) func() error {
-	return func() error {
-		unmodifiedFile, formattedFile, err := LoadFormatGoFile(file, cfg)
-		if err != nil {
-			// if errors.Is(err, FileParsingError{}) {
-			// 	// do not process files that are improperly formatted
-			// 	return nil
-			// }
-			return err
-		}
-		return formattingFunc(file.Path(), unmodifiedFile, formattedFile)
+func jobs(cfg config.Config) int {
+	if cfg.Jobs < 1 {
+		return runtime.GOMAXPROCS(0)
 	}
+	return cfg.Jobs
 }
 
 func LoadFormatGoFile(file io.FileObj, cfg config.Config) (src, dist []byte, err error) {
@@ -127,67 +209,265 @@ func LoadFormatGoFile(file io.FileObj, cfg config.Config) (src, dist []byte, err
 		return nil, nil, err
 	}
 
-	return LoadFormat(src, file.Path(), cfg)
+	// files found in paths are already filtered, but STDIN may belong to a vendored file
+	if cfg.SkipVendor && io.IsVendored(file.Path()) {
+		log.L().Debug(fmt.Sprintf("Skipping vendored File: %s", file.Path()))
+		return src, src, nil
+	}
+
+	embedded := cfg.FormatEmbedded && io.IsEmbeddingFile(file.Path())
+	// the same content may be formatted differently as Go file and as Markdown or txtar file
+	kind := []byte("go")
+	if embedded {
+		kind = []byte("embedded")
+	}
+	var entry [][]byte
+	if cfg.Cache != nil {
+		// files without module fail while formatting, if their imports need it
+		entry, _ = cacheEntry(file.Path(), kind, src, cfg)
+	}
+	if entry != nil && cfg.Cache.Has(entry...) {
+		log.L().Debug(fmt.Sprintf("Skipping cached File: %s", file.Path()))
+		return src, src, nil
+	}
+
+	if embedded {
+		src, dist, err = LoadFormatEmbedded(src, file.Path(), cfg)
+	} else {
+		src, dist, err = LoadFormat(src, file.Path(), cfg)
+	}
+	if err == nil && entry != nil && bytes.Equal(src, dist) {
+		if cacheErr := cfg.Cache.Add(entry...); cacheErr != nil {
+			log.L().Debug(fmt.Sprintf("Failed to cache File %s: %v", file.Path(), cacheErr))
+		}
+	}
+	return src, dist, err
 }
 
 func LoadFormat(in []byte, path string, cfg config.Config) (src, dist []byte, err error) {
 	src = in
 
-	if cfg.SkipGenerated && parse.IsGeneratedFileByComment(string(src)) {
+	if reason, generated := isGenerated(src, path, cfg); generated {
+		log.L().Debug(fmt.Sprintf("Skipping generated File %s: %s", path, reason))
+		return src, src, nil
+	}
+
+	// format a copy without BOM and CRLF line endings and restore them afterwards
+	normalized, layout := normalize(src)
+	ignored, err := parse.IsIgnored(normalized, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ignored {
+		log.L().Debug(fmt.Sprintf("Skipping ignored File: %s", path))
+		return src, src, nil
+	}
+	dist, err = formatImports(normalized, path, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bytes.Equal(normalized, dist) && (cfg.LineEndings == "" || cfg.LineEndings == config.LineEndingsPreserve) {
 		return src, src, nil
 	}
 
-	imports, headEnd, tailStart, cStart, cEnd, err := parse.ParseFile(src, path)
+	return src, layout.restore(dist, cfg.LineEndings), nil
+}
+
+// isGenerated reports whether src is a generated file that SkipGenerated skips, and why.
+func isGenerated(src []byte, path string, cfg config.Config) (string, bool) {
+	if !cfg.SkipGenerated {
+		return "", false
+	}
+	if cfg.GeneratedFiles == config.GeneratedFilesStrict {
+		return parse.GeneratedByHeader(src, path)
+	}
+	return parse.GeneratedByMarker(string(src))
+}
+
+// formatImports formats the imports of src, which must only contain LF line endings.
+// All import declarations are merged into one, unless NoMerge is set.
+func formatImports(src []byte, path string, cfg config.Config) ([]byte, error) {
+	src, changes, conflicts, err := parse.RewriteAliases(src, path, cfg.Aliases)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		log.L().Debug(fmt.Sprintf("%s: %s", path, change))
+	}
+	for _, conflict := range conflicts {
+		log.L().Warn(fmt.Sprintf("%s: %s", path, conflict))
+	}
+
+	// import "C" needs a declaration of its own to keep its preamble when the other imports are sorted
+	src, err = parse.SplitCgoImports(src, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.NoMerge {
+		return formatImportDecls(src, path, cfg)
+	}
+	src, err = parse.MergeImportDecls(src, path)
+	if err != nil {
+		return nil, err
+	}
+	return formatImportBlock(src, path, cfg)
+}
+
+// formatImportDecls formats every import declaration of src on its own.
+func formatImportDecls(src []byte, path string, cfg config.Config) ([]byte, error) {
+	decls, err := parse.ImportDecls(src, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(decls) <= 1 {
+		return formatImportBlock(src, path, cfg)
+	}
+
+	const header = "package p\n\n"
+	dist := src
+	// from the last to the first declaration, so the offsets stay valid
+	for i := len(decls) - 1; i >= 0; i-- {
+		decl := decls[i]
+		formatted, err := formatImportBlock([]byte(header+string(src[decl.Start:decl.End])+"\n"), path, cfg)
+		if err != nil {
+			return nil, err
+		}
+		formatted = bytes.TrimSuffix(bytes.TrimPrefix(formatted, []byte(header)), []byte("\n"))
+		dist = append(dist[:decl.Start:decl.Start], append(formatted, dist[decl.End:]...)...)
+	}
+	// the declarations are sorted already, gofmt would also sort the imports of their pinned segments
+	return parse.FormatKeepingImportOrder(dist, path)
+}
+
+// formatImportBlock formats src, whose import declarations besides import "C" are next to each other.
+func formatImportBlock(src []byte, path string, cfg config.Config) ([]byte, error) {
+	if cfg.Dedupe {
+		var changes []string
+		var err error
+		src, changes, err = parse.DedupeImports(src, path)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range changes {
+			log.L().Debug(fmt.Sprintf("%s: %s", path, change))
+		}
+	}
+
+	segments, err := parse.PinnedSegments(src, path)
+	if err != nil {
+		return nil, err
+	}
+	if segments != nil {
+		return formatAroundPinned(src, path, cfg, segments)
+	}
+
+	// the import "C" declarations are taken out and put back in front of the other imports or at the cgo section
+	cgoDecls, err := parse.CgoImportDecls(src, path)
+	if err != nil {
+		return nil, err
+	}
+	var cgo []byte
+	for _, d := range cgoDecls {
+		cgo = append(cgo, src[d.Start:d.End]...)
+		cgo = append(cgo, utils.Linebreak, utils.Linebreak)
+	}
+	stripped := src
+	for i := len(cgoDecls) - 1; i >= 0; i-- {
+		start, end := cgoDecls[i].Start, cgoDecls[i].End
+		if end < len(stripped) && stripped[end] == utils.Linebreak {
+			end++
+		}
+		stripped = append(stripped[:start:start], stripped[end:]...)
+	}
+
+	imports, headEnd, tailStart, _, _, err := parse.ParseFile(stripped, path)
 	if err != nil {
 		if errors.Is(err, parse.NoImportError{}) {
-			return src, src, nil
+			return src, nil
 		}
-		return nil, nil, err
+		return nil, err
 	}
 
 	// do not do format if only one import
 	if len(imports) <= 1 {
-		return src, src, nil
+		return src, nil
+	}
+
+	// localmodule sections match the module the file belongs to
+	cfg.Sections, err = cfg.Sections.ForFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range cfg.Sections {
+		if m, ok := s.(*section.LocalModule); ok {
+			for _, modPath := range m.Paths {
+				log.L().Debug(fmt.Sprintf("Local module of %s: %s (%s)", path, modPath, m.Rule(modPath)))
+			}
+		}
 	}
 
 	result, err := format.Format(imports, &cfg)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	firstWithIndex := true
 
 	var body []byte
+	// the offset of the body the import "C" declarations go to, they go in front of it without cgo section
+	cgoAt := 0
 
 	// order by section list
 	for _, s := range cfg.Sections {
+		if _, ok := s.(section.Cgo); ok {
+			cgoAt = len(body)
+			continue
+		}
 		if len(result[s.String()]) > 0 {
 			if len(body) > 0 {
 				body = append(body, utils.Linebreak)
 			}
-			for _, d := range result[s.String()] {
+			blocks := result[s.String()]
+			var roots []string
+			if cfg.SubgroupsByModule(s) {
+				blocks, roots, err = groupByModule(blocks, path)
+				if err != nil {
+					return nil, err
+				}
+			}
+			for i, d := range blocks {
+				if i > 0 && roots != nil && roots[i] != roots[i-1] {
+					body = append(body, utils.Linebreak)
+				}
 				AddIndent(&body, &firstWithIndex)
-				body = append(body, src[d.Start:d.End]...)
+				body = append(body, stripped[d.Start:d.End]...)
 			}
 		}
 	}
 
 	head := make([]byte, headEnd)
-	copy(head, src[:headEnd])
-	tail := make([]byte, len(src)-tailStart)
-	copy(tail, src[tailStart:])
+	copy(head, stripped[:headEnd])
+	tail := make([]byte, len(stripped)-tailStart)
+	copy(tail, stripped[tailStart:])
 
-	// ensure C
-	if cStart != 0 {
-		head = append(head, src[cStart:cEnd]...)
+	if cgoAt > 0 && len(cgo) > 0 {
+		// the imports of the sections in front of the cgo section get a declaration of their own
+		head = append(head, `import (`...)
 		head = append(head, utils.Linebreak)
+		head = append(head, body[:cgoAt]...)
+		head = append(head, utils.RightParenthesis, utils.Linebreak, utils.Linebreak)
+		body = bytes.TrimLeft(body[cgoAt:], "\n")
 	}
+	head = append(head, cgo...)
 
-	// add beginning of import block
-	head = append(head, `import (`...)
-	head = append(head, utils.Linebreak)
-	// add end of import block
-	body = append(body, []byte{utils.RightParenthesis, utils.Linebreak}...)
+	if len(body) > 0 {
+		// add beginning of import block
+		head = append(head, `import (`...)
+		head = append(head, utils.Linebreak)
+		// add end of import block
+		body = append(body, []byte{utils.RightParenthesis, utils.Linebreak}...)
+	}
 
 	log.L().Debug(fmt.Sprintf("head:\n%s", head))
 	log.L().Debug(fmt.Sprintf("body:\n%s", body))
@@ -202,22 +482,145 @@ func LoadFormat(in []byte, path string, cfg config.Config) (src, dist []byte, er
 	for _, s := range slices {
 		totalLen += len(s)
 	}
-	dist = make([]byte, totalLen)
+	dist := make([]byte, totalLen)
 	var i int
 	for _, s := range slices {
 		i += copy(dist[i:], s)
 	}
 
-	// remove ^M(\r\n) from Win to Unix
-	dist = bytes.ReplaceAll(dist, []byte{utils.WinLinebreak}, []byte{utils.Linebreak})
-
 	log.L().Debug(fmt.Sprintf("raw:\n%s", dist))
-	dist, err = goFormat.Source(dist)
+	return gofmt(dist, path, cfg)
+}
+
+// formatAroundPinned formats the imports outside of the pinned segments of the import declaration of src as one group
+// and puts them back into the unpinned segments, as many as each held before. The pinned segments keep their place
+// and order.
+func formatAroundPinned(src []byte, path string, cfg config.Config, segments []parse.ImportSegment) ([]byte, error) {
+	const header = "package p\n\nimport (\n"
+	var unpinned []byte
+	var slots []int
+	for _, segment := range segments {
+		if segment.Pinned {
+			continue
+		}
+		text := src[segment.Start:segment.End]
+		entries, err := importEntries([]byte(header+string(text)+")\n"), path)
+		if err != nil {
+			return nil, err
+		}
+		slots = append(slots, len(entries))
+		unpinned = append(unpinned, text...)
+	}
+	formatted, err := formatImportBlock([]byte(header+string(unpinned)+")\n"), path, cfg)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := importEntries(formatted, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var body []byte
+	for _, segment := range segments {
+		var text []byte
+		if segment.Pinned {
+			text = bytes.TrimSpace(src[segment.Start:segment.End])
+		} else {
+			n := min(slots[0], len(entries))
+			if len(slots) == 1 {
+				// the last segment takes what is left
+				n = len(entries)
+			}
+			for i, e := range entries[:n] {
+				if i > 0 {
+					text = append(text, utils.Linebreak)
+					if e.newSection {
+						text = append(text, utils.Linebreak)
+					}
+					text = append(text, utils.Indent)
+				}
+				text = append(text, e.text...)
+			}
+			entries, slots = entries[n:], slots[1:]
+		}
+		if len(text) == 0 {
+			continue
+		}
+		if len(body) > 0 {
+			body = append(body, utils.Linebreak)
+		}
+		body = append(body, utils.Indent)
+		body = append(body, text...)
+		body = append(body, utils.Linebreak)
+	}
+
+	dist := append(src[:segments[0].Start:segments[0].Start], append(body, src[segments[len(segments)-1].End:]...)...)
+	// gofmt would sort the imports of the pinned segments
+	return parse.FormatKeepingImportOrder(dist, path)
+}
+
+// importEntry is the text of an import of a formatted import declaration, with its comments.
+type importEntry struct {
+	text []byte
+	// newSection is set if a blank line separates the import from the one in front of it
+	newSection bool
+}
+
+// importEntries returns the imports of src in the order they are in.
+func importEntries(src []byte, path string) ([]importEntry, error) {
+	imports, _, _, _, _, err := parse.ParseFile(src, path)
+	if err != nil {
+		if errors.Is(err, parse.NoImportError{}) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		return imports[i].Start < imports[j].Start
+	})
+	entries := make([]importEntry, len(imports))
+	end := 0
+	for i, imp := range imports {
+		// the range of an import ends after its linebreak
+		entries[i].text = bytes.TrimRight(src[imp.Start:imp.End], "\n")
+		if i > 0 {
+			entries[i].newSection = bytes.Count(src[end:imp.Start], []byte{utils.Linebreak}) > 1
+		}
+		end = imp.Start + len(entries[i].text)
+	}
+	return entries, nil
+}
+
+// groupByModule orders the blocks by the module root they belong to, see section.ModuleRoot, and returns the root of
+// every block. The blocks of a root keep their order.
+func groupByModule(blocks []*format.Block, path string) ([]*format.Block, []string, error) {
+	modules, err := section.RequiredModules(path)
 	if err != nil {
 		return nil, nil, err
 	}
+	rootOf := make(map[*format.Block]string, len(blocks))
+	for _, b := range blocks {
+		rootOf[b] = section.ModuleRoot(modules, b.Path)
+	}
+	grouped := make([]*format.Block, len(blocks))
+	copy(grouped, blocks)
+	sort.SliceStable(grouped, func(i, j int) bool {
+		return rootOf[grouped[i]] < rootOf[grouped[j]]
+	})
+	roots := make([]string, len(grouped))
+	for i, b := range grouped {
+		roots[i] = rootOf[b]
+	}
+	return grouped, roots, nil
+}
 
-	return src, dist, nil
+// gofmt formats src like gofmt. gofmt sorts consecutive imports by path, so the imports keep their order if a
+// section sorts them in another way.
+func gofmt(src []byte, path string, cfg config.Config) ([]byte, error) {
+	if !cfg.SortsLexically() {
+		return parse.FormatKeepingImportOrder(src, path)
+	}
+	return goFormat.Source(src)
 }
 
 func AddIndent(in *[]byte, first *bool) {
@@ -40,6 +40,58 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestRunKeepsLineEndingsAndBOM(t *testing.T) {
+	toCRLF := func(s string) string {
+		return strings.ReplaceAll(s, "\n", "\r\n")
+	}
+	const bom = "\uFEFF"
+
+	for i := range testCases {
+		t.Run(fmt.Sprintf("run case: %s", testCases[i].name), func(t *testing.T) {
+			cfg, err := config.ParseConfig(testCases[i].config)
+			require.NoError(t, err)
+
+			in := bom + toCRLF(testCases[i].in)
+			_, got, err := LoadFormat([]byte(in), "", *cfg)
+			require.NoError(t, err)
+			assert.Equal(t, bom+toCRLF(testCases[i].out), string(got))
+
+			// formatting the result again must not change it
+			_, again, err := LoadFormat(got, "", *cfg)
+			require.NoError(t, err)
+			assert.Equal(t, string(got), string(again))
+		})
+	}
+}
+
+func TestRunWithLineEndings(t *testing.T) {
+	in := "package main\r\n\r\nimport (\r\n\t\"os\"\r\n\t\"fmt\"\r\n)\r\n"
+	lfOut := "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n"
+
+	for _, tt := range []struct {
+		lineEndings string
+		in, out     string
+	}{
+		{"preserve", in, strings.ReplaceAll(lfOut, "\n", "\r\n")},
+		{"lf", in, lfOut},
+		{"crlf", lfOut, strings.ReplaceAll(lfOut, "\n", "\r\n")},
+		// already formatted files are converted as well
+		{"lf", strings.ReplaceAll(lfOut, "\n", "\r\n"), lfOut},
+	} {
+		t.Run(tt.lineEndings, func(t *testing.T) {
+			cfg, err := config.ParseConfig(commonConfig + "lineEndings: " + tt.lineEndings + "\n")
+			require.NoError(t, err)
+
+			_, got, err := LoadFormat([]byte(tt.in), "", *cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.out, string(got))
+		})
+	}
+
+	_, err := config.ParseConfig(commonConfig + "lineEndings: cr\n")
+	assert.Error(t, err)
+}
+
 func chdir(t *testing.T, dir string) {
 	oldWd, err := os.Getwd()
 	require.NoError(t, err)
@@ -58,42 +110,43 @@ func readConfig(t *testing.T, configPath string) *config.Config {
 	return cfg
 }
 
-func TestRunWithLocalModule(t *testing.T) {
-	tests := []struct {
-		name      string
-		moduleDir string
-		// files with a corresponding '*.out.go' file containing the expected
-		// result of formatting
-		testedFiles []string
-	}{
-		{
-			name:      `default module test case`,
-			moduleDir: filepath.Join("testdata", "module"),
-			testedFiles: []string{
-				"main.go",
-				filepath.Join("internal", "foo", "lib.go"),
-			},
+var localModuleTests = []struct {
+	name      string
+	moduleDir string
+	// files with a corresponding '*.out.go' file containing the expected
+	// result of formatting
+	testedFiles []string
+}{
+	{
+		name:      `default module test case`,
+		moduleDir: filepath.Join("testdata", "module"),
+		testedFiles: []string{
+			"main.go",
+			filepath.Join("internal", "foo", "lib.go"),
 		},
-		{
-			name:      `canonical module without go sources in root dir`,
-			moduleDir: filepath.Join("testdata", "module_canonical"),
-			testedFiles: []string{
-				filepath.Join("cmd", "client", "main.go"),
-				filepath.Join("cmd", "server", "main.go"),
-				filepath.Join("internal", "foo", "lib.go"),
-			},
+	},
+	{
+		name:      `canonical module without go sources in root dir`,
+		moduleDir: filepath.Join("testdata", "module_canonical"),
+		testedFiles: []string{
+			filepath.Join("cmd", "client", "main.go"),
+			filepath.Join("cmd", "server", "main.go"),
+			filepath.Join("internal", "foo", "lib.go"),
 		},
-		{
-			name:      `non-canonical module without go sources in root dir`,
-			moduleDir: filepath.Join("testdata", "module_noncanonical"),
-			testedFiles: []string{
-				filepath.Join("cmd", "client", "main.go"),
-				filepath.Join("cmd", "server", "main.go"),
-				filepath.Join("internal", "foo", "lib.go"),
-			},
+	},
+	{
+		name:      `non-canonical module without go sources in root dir`,
+		moduleDir: filepath.Join("testdata", "module_noncanonical"),
+		testedFiles: []string{
+			filepath.Join("cmd", "client", "main.go"),
+			filepath.Join("cmd", "server", "main.go"),
+			filepath.Join("internal", "foo", "lib.go"),
 		},
-	}
-	for _, tt := range tests {
+	},
+}
+
+func TestRunWithLocalModule(t *testing.T) {
+	for _, tt := range localModuleTests {
 		t.Run(tt.name, func(t *testing.T) {
 			// run subtests for expected module loading behaviour
 			chdir(t, tt.moduleDir)
@@ -105,7 +158,7 @@ func TestRunWithLocalModule(t *testing.T) {
 					expected, err := os.ReadFile(strings.TrimSuffix(path, ".go") + ".out.go")
 					require.NoError(t, err)
 
-					_, got, err := LoadFormatGoFile(io.File{path}, *cfg)
+					_, got, err := LoadFormatGoFile(io.File{FilePath: path}, *cfg)
 
 					require.NoError(t, err)
 					require.Equal(t, string(expected), string(got))
@@ -115,12 +168,195 @@ func TestRunWithLocalModule(t *testing.T) {
 	}
 }
 
+func TestRunWithLocalModuleFromOtherDirectory(t *testing.T) {
+	// every file uses the module it belongs to, regardless of the working directory
+	cfg, err := config.ParseConfig("sections:\n  - Standard\n  - Default\n  - LocalModule\n")
+	require.NoError(t, err)
+
+	for _, tt := range localModuleTests {
+		for _, path := range tt.testedFiles {
+			path = filepath.Join(tt.moduleDir, path)
+			t.Run(path, func(t *testing.T) {
+				expected, err := os.ReadFile(strings.TrimSuffix(path, ".go") + ".out.go")
+				require.NoError(t, err)
+
+				_, got, err := LoadFormatGoFile(io.File{FilePath: path}, *cfg)
+
+				require.NoError(t, err)
+				require.Equal(t, string(expected), string(got))
+			})
+		}
+	}
+}
+
 func TestRunWithLocalModuleWithPackageLoadFailure(t *testing.T) {
 	// just a directory with no Go modules
 	dir := t.TempDir()
 	configContent := "sections:\n  - LocalModule\n"
 
-	chdir(t, dir)
-	_, err := config.ParseConfig(configContent)
+	cfg, err := config.ParseConfig(configContent)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n"), 0o644))
+	_, _, err = LoadFormatGoFile(io.File{FilePath: path}, *cfg)
 	require.ErrorContains(t, err, "could not find module path for `localModule` configuration")
 }
+
+func TestProcessFilesKeepsGeneratorOrder(t *testing.T) {
+	dir := t.TempDir()
+	var want []string
+	for d := 0; d < 5; d++ {
+		for f := 0; f < 20; f++ {
+			path := filepath.Join(dir, fmt.Sprintf("pkg%d", d), fmt.Sprintf("file%02d.go", f))
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			// files of different sizes take different times to format
+			imports := strings.Repeat("\t\"os\"\n\t\"fmt\"\n", f%7+1)
+			require.NoError(t, os.WriteFile(path, []byte("package p\n\nimport (\n"+imports+")\n"), 0o644))
+			want = append(want, path)
+		}
+	}
+
+	cfg, err := config.ParseConfig("")
+	require.NoError(t, err)
+	cfg.Jobs = 8
+	for i := 0; i < 50; i++ {
+		var got []string
+		err := ProcessFiles(io.GoFilesInPathsGenerator([]string{dir}, false), *cfg, func(filePath string, _, _ []byte) error {
+			got = append(got, filePath)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, want, got, "run %d", i)
+	}
+}
+
+func TestLoadFormatGoFileSkipsVendoredPath(t *testing.T) {
+	unformatted := "package a\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n"
+	cfg, err := config.ParseConfig("skipVendor: true\n")
+	require.NoError(t, err)
+
+	// STDIN may claim to be a vendored file
+	_, dist, err := LoadFormatGoFile(memoryFile{filepath.Join("vendor", "a", "a.go"), unformatted}, *cfg)
+	require.NoError(t, err)
+	assert.Equal(t, unformatted, string(dist))
+
+	_, dist, err = LoadFormatGoFile(memoryFile{filepath.Join("pkg", "a", "a.go"), unformatted}, *cfg)
+	require.NoError(t, err)
+	assert.NotEqual(t, unformatted, string(dist))
+}
+
+func TestLoadFormatSkipsGeneratedFiles(t *testing.T) {
+	unformatted := "package a\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n"
+	generated := "// Code generated by protoc-gen-go. DO NOT EDIT.\n\n" + unformatted
+	// mentions the markers, but only in a string
+	handWritten := unformatted + "\nconst warning = \"Code generated files: do not edit\"\n"
+	// the comment does not match the canonical form
+	lowercase := "// code generated, do not edit\n" + unformatted
+
+	testCases := []struct {
+		config   string
+		src      string
+		expected bool
+	}{
+		// lax by default, like before there was a choice
+		{"skipGenerated: true\n", generated, true},
+		{"skipGenerated: true\n", handWritten, true},
+		{"skipGenerated: true\n", lowercase, true},
+		{"skipGenerated: true\ngeneratedFiles: strict\n", generated, true},
+		{"skipGenerated: true\ngeneratedFiles: strict\n", handWritten, false},
+		{"skipGenerated: true\ngeneratedFiles: strict\n", lowercase, false},
+		{"skipGenerated: true\ngeneratedFiles: lax\n", handWritten, true},
+		{"skipGenerated: false\n", generated, false},
+	}
+	for _, tc := range testCases {
+		cfg, err := config.ParseConfig(tc.config)
+		require.NoError(t, err)
+		src, dist, err := LoadFormat([]byte(tc.src), "a.go", *cfg)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, string(src) == string(dist), "%q of %q", tc.config, tc.src)
+	}
+
+	_, err := config.ParseConfig("generatedFiles: comments\n")
+	assert.Error(t, err)
+}
+
+func TestRunWithLocalModuleScopes(t *testing.T) {
+	dir := t.TempDir()
+	for path, content := range map[string]string{
+		"go.work":         "go 1.21\n\nuse (\n\t./app\n\t./lib\n)\n",
+		"app/go.mod":      "module example.com/app\n",
+		"lib/go.mod":      "module example.com/lib\n",
+		"app/cmd/main.go": "package main\n\nimport (\n\t\"example.com/app/internal\"\n\t\"example.com/lib\"\n\t\"fmt\"\n\t\"github.com/foo/bar\"\n)\n",
+	} {
+		path = filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	cfg, err := config.ParseConfig("customOrder: true\nsections:\n  - standard\n  - default\n  - localmodule(workspace)\n  - localmodule(self)\n")
+	require.NoError(t, err)
+
+	_, got, err := LoadFormatGoFile(io.File{FilePath: filepath.Join(dir, "app", "cmd", "main.go")}, *cfg)
+	require.NoError(t, err)
+	require.Equal(t, "package main\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/foo/bar\"\n\n\t\"example.com/lib\"\n\n\t\"example.com/app/internal\"\n)\n", string(got))
+}
+
+func TestLoadFormatSubgroupByModule(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(`module example.com/app
+
+go 1.22
+
+require (
+	github.com/aws/aws-sdk-go v1.55.0
+	github.com/stretchr/testify v1.9.0
+	go.uber.org/zap v1.27.0
+)
+`), 0o644))
+	// the modules that are not required are grouped by their host or repository, never with another module
+	src := `package main
+
+import (
+	"os"
+	"go.uber.org/zap"
+	"github.com/stretchr/testify/assert"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"go.uber.org/multierr"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/smithy-go"
+	"github.com/zed/x"
+	"gitlab.com/group/project"
+)
+`
+	expected := `package main
+
+import (
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/aws/smithy-go"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zed/x"
+
+	"gitlab.com/group/project"
+
+	"go.uber.org/multierr"
+
+	"go.uber.org/zap"
+)
+`
+
+	cfg, err := config.ParseConfig("subgroupByModule: [default]\n")
+	require.NoError(t, err)
+	_, dist, err := LoadFormat([]byte(src), filepath.Join(dir, "main.go"), *cfg)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(dist))
+
+	_, err = config.ParseConfig("subgroupByModule: [blank]\n")
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package gci
+
+import (
+	"bytes"
+
+	"github.com/daixiang0/gci/pkg/config"
+)
+
+var (
+	bom  = []byte{0xEF, 0xBB, 0xBF}
+	lf   = []byte{'\n'}
+	crlf = []byte{'\r', '\n'}
+)
+
+// textLayout records the byte order mark and the line endings of a file,
+// so they can be restored after formatting the normalized content.
+type textLayout struct {
+	bom  bool
+	crlf bool
+}
+
+// normalize strips a leading byte order mark and converts all CRLF line endings to LF.
+// Most of the lines must end with CRLF for the file to be considered a CRLF file.
+func normalize(src []byte) ([]byte, textLayout) {
+	var layout textLayout
+	if bytes.HasPrefix(src, bom) {
+		layout.bom = true
+		src = src[len(bom):]
+	}
+	crlfCount := bytes.Count(src, crlf)
+	layout.crlf = crlfCount > 0 && crlfCount*2 >= bytes.Count(src, lf)
+	return bytes.ReplaceAll(src, crlf, lf), layout
+}
+
+// restore converts normalized content back to the layout selected by lineEndings.
+func (l textLayout) restore(src []byte, lineEndings config.LineEndings) []byte {
+	useCRLF := l.crlf
+	switch lineEndings {
+	case config.LineEndingsLF:
+		useCRLF = false
+	case config.LineEndingsCRLF:
+		useCRLF = true
+	}
+	if useCRLF {
+		src = bytes.ReplaceAll(src, lf, crlf)
+	}
+	if l.bom {
+		src = append(append([]byte{}, bom...), src...)
+	}
+	return src
+}
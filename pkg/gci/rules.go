@@ -0,0 +1,38 @@
+package gci
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/daixiang0/gci/pkg/config"
+	"github.com/daixiang0/gci/pkg/parse"
+	"github.com/daixiang0/gci/pkg/rules"
+)
+
+// CheckImports returns the imports of the Go file at path that break the rules of the configuration.
+// Markdown and txtar files are not checked, and generated files only if they are formatted. Files ignored with
+// //gci:ignore or //nolint:gci are not checked either.
+func CheckImports(path string, src []byte, cfg config.Config) ([]rules.Violation, error) {
+	if len(cfg.Rules) == 0 || !strings.HasSuffix(path, ".go") {
+		return nil, nil
+	}
+	if _, generated := isGenerated(src, path, cfg); generated {
+		return nil, nil
+	}
+	if ignored, err := parse.IsIgnored(src, path); err != nil || ignored {
+		return nil, err
+	}
+	return rules.Check(src, path, cfg.Rules)
+}
+
+// ViolationsError reports that imports break the rules of the configuration, which are printed already.
+type ViolationsError struct {
+	Count int
+}
+
+func (e *ViolationsError) Error() string {
+	if e.Count == 1 {
+		return "1 import breaks the rules"
+	}
+	return fmt.Sprintf("%d imports break the rules", e.Count)
+}
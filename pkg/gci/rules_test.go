@@ -0,0 +1,43 @@
+package gci
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daixiang0/gci/pkg/config"
+)
+
+func TestListUnFormattedFilesFailsOnViolations(t *testing.T) {
+	dir := t.TempDir()
+	src := "package lib\n\nimport \"io/ioutil\"\n\nvar _ = ioutil.Discard\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib.go"), []byte(src), 0o644))
+
+	cfg, err := config.ParseConfig("rules:\n  - type: package\n    path: io/ioutil\n")
+	require.NoError(t, err)
+	err = ListUnFormattedFiles([]string{dir}, *cfg)
+	var violations *ViolationsError
+	require.ErrorAs(t, err, &violations)
+	assert.Equal(t, 1, violations.Count)
+	assert.Empty(t, FileErrors(err))
+
+	cfg, err = config.ParseConfig("rules:\n  - type: dot\n")
+	require.NoError(t, err)
+	assert.NoError(t, ListUnFormattedFiles([]string{dir}, *cfg))
+}
+
+func TestListUnFormattedFilesFailsOnBlankImportsWithoutComment(t *testing.T) {
+	dir := t.TempDir()
+	src := "package main\n\nimport (\n\t_ \"github.com/lib/pq\"\n\n\t// registers the driver\n\t_ \"github.com/go-sql-driver/mysql\"\n)\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0o644))
+
+	cfg, err := config.ParseConfig("rules:\n  - type: blankcomment\n")
+	require.NoError(t, err)
+	err = ListUnFormattedFiles([]string{dir}, *cfg)
+	var violations *ViolationsError
+	require.ErrorAs(t, err, &violations)
+	assert.Equal(t, 1, violations.Count)
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package io
+
+import "os"
+
+// chown is a no-op on platforms without unix file ownership.
+func chown(_ *os.File, _ os.FileInfo) error {
+	return nil
+}
@@ -1,6 +1,9 @@
 package io
 
-import "io/ioutil"
+import (
+	"iter"
+	"os"
+)
 
 // FileObj allows mocking the access to files
 type FileObj interface {
@@ -18,24 +21,25 @@ func (f File) Path() string {
 }
 
 func (f File) Load() ([]byte, error) {
-	return ioutil.ReadFile(f.FilePath)
+	return os.ReadFile(f.FilePath)
 }
 
-// FileGeneratorFunc returns a list of files that can be loaded and processed
-type FileGeneratorFunc func() ([]FileObj, error)
+// FileGeneratorFunc streams the files that can be loaded and processed.
+// Errors that occur while searching for files are yielded as well.
+type FileGeneratorFunc iter.Seq2[FileObj, error]
 
 func (a FileGeneratorFunc) Combine(b FileGeneratorFunc) FileGeneratorFunc {
-	return func() ([]FileObj, error) {
-		files, err := a()
-		if err != nil {
-			return nil, err
+	return func(yield func(FileObj, error) bool) {
+		for file, err := range a {
+			if !yield(file, err) {
+				return
+			}
 		}
-		additionalFiles, err := b()
-		if err != nil {
-			return nil, err
+		for file, err := range b {
+			if !yield(file, err) {
+				return
+			}
 		}
-		files = append(files, additionalFiles...)
-		return files, err
 	}
 }
 
@@ -48,17 +52,31 @@ func GoFilesInPathsGenerator(paths []string, skipVendor bool) FileGeneratorFunc
 	return FilesInPathsGenerator(paths, checkFunc)
 }
 
+// GoAndEmbeddingFilesInPathsGenerator also returns Markdown and txtar files, which may embed Go files.
+func GoAndEmbeddingFilesInPathsGenerator(paths []string, skipVendor bool) FileGeneratorFunc {
+	checkFunc := anyOf(isGoFile, isEmbeddingFile)
+	if skipVendor {
+		checkFunc = checkChains(checkFunc, isOutsideVendorDir)
+	}
+
+	return FilesInPathsGenerator(paths, checkFunc)
+}
+
+// FilesInPathsGenerator walks the paths lazily, every file is yielded as soon as it is found.
 func FilesInPathsGenerator(paths []string, fileCheckFun fileCheckFunction) FileGeneratorFunc {
-	return func() (foundFiles []FileObj, err error) {
+	return func(yield func(FileObj, error) bool) {
 		for _, path := range paths {
-			files, err := FindFilesForPath(path, fileCheckFun)
-			if err != nil {
-				return nil, err
+			stopped := false
+			err := WalkFilesForPath(path, fileCheckFun, func(filePath string) bool {
+				stopped = !yield(File{filePath}, nil)
+				return !stopped
+			})
+			if stopped {
+				return
 			}
-			for _, filePath := range files {
-				foundFiles = append(foundFiles, File{filePath})
+			if err != nil && !yield(nil, err) {
+				return
 			}
 		}
-		return foundFiles, nil
 	}
 }
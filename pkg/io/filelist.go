@@ -0,0 +1,44 @@
+package io
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReadFileList reads the paths listed in the file at path, "-" reads them from STDIN.
+func ReadFileList(path string) ([]string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseFileList(data), nil
+}
+
+// ParseFileList splits a list of paths. The paths are separated by NUL bytes, as written by `find -print0`
+// or `git diff -z`, if the list contains any, and by newlines otherwise. Empty entries are dropped.
+func ParseFileList(data []byte) []string {
+	sep := []byte{'\n'}
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = []byte{0}
+	}
+
+	var paths []string
+	for _, entry := range bytes.Split(data, sep) {
+		path := string(entry)
+		if sep[0] == '\n' {
+			path = strings.TrimSuffix(path, "\r")
+		}
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,26 @@
+package io
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFileList(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"newlines", "a.go\nb/c.go\n", []string{"a.go", "b/c.go"}},
+		{"crlf and blank lines", "a.go\r\n\r\n\nb.go", []string{"a.go", "b.go"}},
+		{"nul", "a.go\x00with\nnewline.go\x00", []string{"a.go", "with\nnewline.go"}},
+		{"spaces are kept", " a.go\n", []string{" a.go"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ParseFileList([]byte(tc.in)))
+		})
+	}
+}
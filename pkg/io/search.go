@@ -4,48 +4,77 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type fileCheckFunction func(path string, file os.FileInfo) bool
 
 func FindFilesForPath(path string, fileCheckFun fileCheckFunction) ([]string, error) {
+	filePaths := []string{}
+	err := WalkFilesForPath(path, fileCheckFun, func(filePath string) bool {
+		filePaths = append(filePaths, filePath)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return filePaths, nil
+}
+
+// WalkFilesForPath calls fn for every file below path that passes the check, until fn returns false.
+func WalkFilesForPath(path string, fileCheckFun fileCheckFunction, fn func(filePath string) bool) error {
 	switch entry, err := os.Stat(path); {
 	case err != nil:
-		return nil, err
+		return err
 	case entry.IsDir():
-		return findFilesForDirectory(path, fileCheckFun)
+		return walkFilesForDirectory(path, fileCheckFun, fn)
 	case fileCheckFun(path, entry):
-		return []string{filepath.Clean(path)}, nil
-	default:
-		return []string{}, nil
+		fn(filepath.Clean(path))
 	}
+	return nil
 }
 
-func findFilesForDirectory(dirPath string, fileCheckFun fileCheckFunction) ([]string, error) {
-	var filePaths []string
-	err := filepath.WalkDir(dirPath, func(path string, entry fs.DirEntry, err error) error {
+func walkFilesForDirectory(dirPath string, fileCheckFun fileCheckFunction, fn func(filePath string) bool) error {
+	return filepath.WalkDir(dirPath, func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
+		if entry.IsDir() {
+			return nil
+		}
 		file, err := entry.Info()
 		if err != nil {
 			return err
 		}
-		if !entry.IsDir() && fileCheckFun(path, file) {
-			filePaths = append(filePaths, filepath.Clean(path))
+		if fileCheckFun(path, file) && !fn(filepath.Clean(path)) {
+			return filepath.SkipAll
 		}
 		return nil
 	})
-	if err != nil {
-		return nil, err
-	}
-	return filePaths, nil
 }
 
 func isGoFile(_ string, file os.FileInfo) bool {
 	return !file.IsDir() && filepath.Ext(file.Name()) == ".go"
 }
 
+// IsEmbeddingFile reports whether the file is a Markdown or txtar file that may contain Go files.
+func IsEmbeddingFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".txtar":
+		return true
+	}
+	return false
+}
+
+func isEmbeddingFile(path string, file os.FileInfo) bool {
+	return !file.IsDir() && IsEmbeddingFile(path)
+}
+
+// IsVendored reports whether the path is inside a vendor directory.
+func IsVendored(path string) bool {
+	return !isOutsideVendorDir(path, nil)
+}
+
 func isOutsideVendorDir(path string, _ os.FileInfo) bool {
 	for {
 		base := filepath.Base(path)
@@ -75,3 +104,15 @@ func checkChains(funcs ...fileCheckFunction) fileCheckFunction {
 		return true
 	}
 }
+
+func anyOf(funcs ...fileCheckFunction) fileCheckFunction {
+	return func(path string, file os.FileInfo) bool {
+		for _, checkFunc := range funcs {
+			if checkFunc(path, file) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
@@ -1,27 +1,39 @@
 package io
 
 import (
-	"io/ioutil"
+	"io"
 	"os"
 )
 
-type stdInFile struct{}
+type stdInFile struct {
+	// path is the file the content of STDIN belongs to, if known
+	path string
+}
 
 func (s stdInFile) Load() ([]byte, error) {
-	return ioutil.ReadAll(os.Stdin)
+	return io.ReadAll(os.Stdin)
 }
 
 func (s stdInFile) Path() string {
-	return "StdIn"
+	if s.path == "" {
+		return "StdIn"
+	}
+	return s.path
 }
 
-var StdInGenerator FileGeneratorFunc = func() ([]FileObj, error) {
-	stat, err := os.Stdin.Stat()
-	if err != nil {
-		return nil, err
-	}
-	if (stat.Mode() & os.ModeCharDevice) == 0 {
-		return []FileObj{stdInFile{}}, nil
+var StdInGenerator = StdInGeneratorWithPath("")
+
+// StdInGeneratorWithPath reads the content of a file from STDIN, but reports path as its location.
+// This allows editors to format unsaved buffers in the context of the file they belong to.
+func StdInGeneratorWithPath(path string) FileGeneratorFunc {
+	return func(yield func(FileObj, error) bool) {
+		stat, err := os.Stdin.Stat()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if (stat.Mode() & os.ModeCharDevice) == 0 {
+			yield(stdInFile{path: path}, nil)
+		}
 	}
-	return []FileObj{}, nil
 }
@@ -0,0 +1,12 @@
+package io
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStdInFilePath(t *testing.T) {
+	assert.Equal(t, "StdIn", stdInFile{}.Path())
+	assert.Equal(t, "pkg/a.go", stdInFile{path: "pkg/a.go"}.Path())
+}
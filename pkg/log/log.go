@@ -24,6 +24,8 @@ func InitLogger() {
 		logConfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 		logConfig.Level.SetLevel(zapcore.InfoLevel)
 		logConfig.OutputPaths = []string{"stderr"}
+		// warnings are meant for users, a stacktrace of gci does not help them
+		logConfig.DisableStacktrace = true
 
 		var err error
 		logger, err = logConfig.Build()
@@ -0,0 +1,101 @@
+package parse
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// SplitCgoImports moves every import "C" that shares an import declaration with other imports into a declaration of
+// its own in front of it. The doc comment of the import is its cgo preamble, so it moves along.
+func SplitCgoImports(src []byte, filename string) ([]byte, error) {
+	fileSet := token.NewFileSet()
+	f, err := ParseWithLateImports(fileSet, filename, src)
+	if err != nil {
+		return nil, err
+	}
+	tokFile := fileSet.File(f.Pos())
+
+	var edits []edit
+	for _, decl := range f.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.IMPORT || !genDecl.Lparen.IsValid() {
+			continue
+		}
+		var cgo strings.Builder
+		others := 0
+		var removed []edit
+		for _, spec := range genDecl.Specs {
+			imp := spec.(*ast.ImportSpec)
+			if imp.Path.Value != C {
+				others++
+				continue
+			}
+			start, end := specRange(tokFile, imp)
+			if imp.Doc != nil {
+				doc := src[tokFile.Offset(imp.Doc.Pos()):tokFile.Offset(imp.Doc.End())]
+				for _, line := range strings.Split(string(doc), "\n") {
+					cgo.WriteString(strings.TrimPrefix(line, "\t") + "\n")
+				}
+			}
+			cgo.WriteString("import " + string(src[tokFile.Offset(imp.Pos()):end]) + "\n\n")
+			start, end = ownLines(src, start, end)
+			removed = append(removed, edit{start, end, ""})
+		}
+		if len(removed) == 0 || others == 0 {
+			continue
+		}
+
+		declStart := tokFile.Offset(genDecl.Pos())
+		if genDecl.Doc != nil {
+			declStart = lineStart(src, tokFile.Offset(genDecl.Doc.Pos()))
+		}
+		edits = append(edits, edit{declStart, declStart, cgo.String()})
+		edits = append(edits, removed...)
+	}
+	if len(edits) == 0 {
+		return src, nil
+	}
+
+	// from the last to the first edit, so the offsets stay valid
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	out := src
+	for _, e := range edits {
+		out = splice(out, e.start, e.end, []byte(e.text))
+	}
+	return out, nil
+}
+
+// CgoImportDecls returns the ranges of the import "C" declarations of src, including their cgo preamble.
+func CgoImportDecls(src []byte, filename string) ([]ImportDecl, error) {
+	fileSet := token.NewFileSet()
+	f, err := ParseWithLateImports(fileSet, filename, src)
+	if err != nil {
+		return nil, err
+	}
+	tokFile := fileSet.File(f.Pos())
+
+	var ranges []ImportDecl
+	for _, decl := range f.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.IMPORT || len(genDecl.Specs) == 0 {
+			continue
+		}
+		isC := false
+		for _, spec := range genDecl.Specs {
+			isC = isC || spec.(*ast.ImportSpec).Path.Value == C
+		}
+		if !isC {
+			continue
+		}
+		start, end := declRange(tokFile, src, genDecl)
+		if genDecl.Doc != nil {
+			start = tokFile.Offset(genDecl.Doc.Pos())
+		}
+		ranges = append(ranges, ImportDecl{start, end})
+	}
+	return ranges, nil
+}
@@ -0,0 +1,201 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DedupeImports removes imports that are listed twice in the same import declaration and aliases that equal the
+// name of the package, like yaml "gopkg.in/yaml.v3". The comments of a removed import are added to the one that is
+// kept. Imports marked with //nolint:gci are left alone. It returns the changed source and a description of every
+// change.
+func DedupeImports(src []byte, filename string) ([]byte, []string, error) {
+	fileSet := token.NewFileSet()
+	f, err := ParseWithLateImports(fileSet, filename, src)
+	if err != nil {
+		return nil, nil, err
+	}
+	tokFile := fileSet.File(f.Pos())
+	offset := func(pos token.Pos) int {
+		return tokFile.Offset(pos)
+	}
+
+	var (
+		edits   []edit
+		changes []string
+	)
+	for _, decl := range importDecls(f) {
+		kept := map[string]*dedupedImport{}
+		var order []*dedupedImport
+		for _, spec := range decl.Specs {
+			imp := spec.(*ast.ImportSpec)
+			name := ""
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			importPath, err := strconv.Unquote(imp.Path.Value)
+			if err != nil || HasNolint(imp.Doc, imp.Comment) {
+				continue
+			}
+			redundant := name != "" && name == PackageName(importPath)
+			if redundant {
+				name = ""
+			}
+
+			key := name + " " + importPath
+			if first, ok := kept[key]; ok {
+				first.merge(imp)
+				start, end := specRange(tokFile, imp)
+				start, end = ownLines(src, start, end)
+				edits = append(edits, edit{start, end, ""})
+				changes = append(changes, fmt.Sprintf("removed duplicate import %s", src[offset(imp.Pos()):offset(imp.Path.End())]))
+				continue
+			}
+			d := &dedupedImport{spec: imp, redundantAlias: redundant}
+			kept[key] = d
+			order = append(order, d)
+			if redundant {
+				changes = append(changes, fmt.Sprintf("removed redundant alias %s of %s", imp.Name.Name, imp.Path.Value))
+			}
+		}
+
+		for _, d := range order {
+			edits = append(edits, d.edits(src, offset)...)
+		}
+	}
+	if len(edits) == 0 {
+		return src, nil, nil
+	}
+
+	// from the last to the first edit, so the offsets stay valid
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	out := src
+	for _, e := range edits {
+		out = splice(out, e.start, e.end, []byte(e.text))
+	}
+	return out, changes, nil
+}
+
+// PackageName returns the name of the package at importPath by the conventions of import paths: the last element
+// without a version suffix like in gopkg.in/yaml.v3. It is empty for paths ending in a version element, as the
+// package of math/rand/v2 is called rand, but the one of k8s.io/api/autoscaling/v2 is called v2.
+func PackageName(importPath string) string {
+	name := path.Base(importPath)
+	if isMajorVersion(name) {
+		return ""
+	}
+	if i := strings.LastIndex(name, "."); i > 0 && isMajorVersion(name[i+1:]) {
+		name = name[:i]
+	}
+	return name
+}
+
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(s[1:])
+	return err == nil
+}
+
+type edit struct {
+	start, end int
+	text       string
+}
+
+// dedupedImport is an import that is kept, together with the comments of its removed duplicates.
+type dedupedImport struct {
+	spec           *ast.ImportSpec
+	redundantAlias bool
+	docs           []string
+	comment        string
+}
+
+func (d *dedupedImport) merge(duplicate *ast.ImportSpec) {
+	if duplicate.Doc != nil {
+		for _, c := range duplicate.Doc.List {
+			if !d.hasComment(c.Text) {
+				d.docs = append(d.docs, c.Text)
+			}
+		}
+	}
+	if duplicate.Comment == nil {
+		return
+	}
+	for _, c := range duplicate.Comment.List {
+		switch {
+		case d.hasComment(c.Text):
+		case d.spec.Comment == nil && d.comment == "":
+			d.comment = c.Text
+		default:
+			// there is only room for one comment at the end of the line
+			d.docs = append(d.docs, c.Text)
+		}
+	}
+}
+
+func (d *dedupedImport) hasComment(text string) bool {
+	for _, group := range []*ast.CommentGroup{d.spec.Doc, d.spec.Comment} {
+		if group == nil {
+			continue
+		}
+		for _, c := range group.List {
+			if c.Text == text {
+				return true
+			}
+		}
+	}
+	for _, doc := range d.docs {
+		if doc == text {
+			return true
+		}
+	}
+	return d.comment == text
+}
+
+func (d *dedupedImport) edits(src []byte, offset func(token.Pos) int) []edit {
+	var edits []edit
+	start := offset(d.spec.Pos())
+	if d.redundantAlias || len(d.docs) > 0 {
+		end := start
+		if d.redundantAlias {
+			end = offset(d.spec.Path.Pos())
+		}
+		lineStart := strings.LastIndexByte(string(src[:start]), '\n') + 1
+		indent := src[lineStart:start]
+		if strings.TrimLeft(string(indent), " \t") != "" {
+			// the import does not start its line, so the docs go in front of it
+			indent = []byte(" ")
+		}
+		var docs strings.Builder
+		for _, doc := range d.docs {
+			docs.WriteString(doc + "\n" + string(indent))
+		}
+		edits = append(edits, edit{start, end, docs.String()})
+	}
+	if d.comment != "" {
+		end := offset(d.spec.Path.End())
+		edits = append(edits, edit{end, end, " " + d.comment})
+	}
+	return edits
+}
+
+// ownLines extends the range to the lines it is on, including the last linebreak, if nothing else is on them.
+func ownLines(src []byte, start, end int) (int, int) {
+	lineStart := strings.LastIndexByte(string(src[:start]), '\n') + 1
+	lineEnd := len(src)
+	if i := strings.IndexByte(string(src[end:]), '\n'); i >= 0 {
+		lineEnd = end + i + 1
+	}
+	if strings.TrimSpace(string(src[lineStart:start])) != "" || strings.TrimSpace(string(src[end:lineEnd])) != "" {
+		return start, end
+	}
+	return lineStart, lineEnd
+}
@@ -0,0 +1,53 @@
+package parse
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// generatedHeader is the comment that marks generated files, see https://go.dev/s/generatedcode.
+var generatedHeader = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// GeneratedByHeader reports whether src is generated code by the rules of ast.IsGenerated: a line comment before the
+// package clause must match ^// Code generated .* DO NOT EDIT\.$. It also returns why the file is generated.
+func GeneratedByHeader(src []byte, filename string) (string, bool) {
+	fileSet := token.NewFileSet()
+	f, err := parser.ParseFile(fileSet, filename, src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return "", false
+	}
+	for _, group := range f.Comments {
+		if group.Pos() > f.Package {
+			break
+		}
+		for _, c := range group.List {
+			if generatedHeader.MatchString(c.Text) {
+				return fmt.Sprintf("line %d is %q", fileSet.Position(c.Pos()).Line, c.Text), true
+			}
+		}
+	}
+	return "", false
+}
+
+// GeneratedByMarker reports whether src mentions one of the markers of generated code anywhere, ignoring case, like
+// IsGeneratedFileByComment. It also returns the marker.
+func GeneratedByMarker(src string) (string, bool) {
+	const (
+		genCodeGenerated = "code generated"
+		genDoNotEdit     = "do not edit"
+		genAutoFile      = "autogenerated file"      // easyjson
+		genAutoGenerated = "automatically generated" // genny
+	)
+
+	markers := []string{genCodeGenerated, genDoNotEdit, genAutoFile, genAutoGenerated}
+	src = strings.ToLower(src)
+	for _, marker := range markers {
+		if strings.Contains(src, marker) {
+			return fmt.Sprintf("it contains %q", marker), true
+		}
+	}
+	return "", false
+}
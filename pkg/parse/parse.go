@@ -170,22 +170,8 @@ func ParseFile(src []byte, filename string) (ImportList, int, int, int, int, err
 // match more generated code.
 // Taken from https://github.com/golangci/golangci-lint.
 func IsGeneratedFileByComment(in string) bool {
-	const (
-		genCodeGenerated = "code generated"
-		genDoNotEdit     = "do not edit"
-		genAutoFile      = "autogenerated file"      // easyjson
-		genAutoGenerated = "automatically generated" // genny
-	)
-
-	markers := []string{genCodeGenerated, genDoNotEdit, genAutoFile, genAutoGenerated}
-	in = strings.ToLower(in)
-	for _, marker := range markers {
-		if strings.Contains(in, marker) {
-			return true
-		}
-	}
-
-	return false
+	_, generated := GeneratedByMarker(in)
+	return generated
 }
 
 type NoImportError struct{}
@@ -0,0 +1,236 @@
+package rules
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/daixiang0/gci/pkg/parse"
+)
+
+// Kind is the kind of imports a rule bans.
+type Kind string
+
+const (
+	// KindDot bans dot imports outside of _test.go files
+	KindDot Kind = "dot"
+	// KindBlank bans blank imports outside of package main and files that only consist of imports and init functions
+	KindBlank Kind = "blank"
+	// KindPackage bans the imports of a package and the packages below it, e.g. deprecated ones like io/ioutil
+	KindPackage Kind = "package"
+	// KindBlankComment bans blank imports without a doc or trailing comment that explains their side effect
+	KindBlankComment Kind = "blankcomment"
+)
+
+// Exemptions of blankcomment rules.
+const (
+	// ExemptEmbed allows blank imports of embed without comment, as //go:embed already explains them
+	ExemptEmbed = "embed"
+	// ExemptTests allows blank imports without comment in _test.go files
+	ExemptTests = "tests"
+)
+
+// Rule bans a kind of imports. Violations are reported with the message of the rule and, if set, the
+// replacement that should be imported instead.
+type Rule struct {
+	Kind        Kind   `yaml:"type"`
+	Path        string `yaml:"path"`
+	Message     string `yaml:"message"`
+	Replacement string `yaml:"replacement"`
+	// Exempt lists the exemptions of blankcomment rules, embed and tests
+	Exempt []string `yaml:"exempt"`
+}
+
+// Parse parses a rule of the form dot, blank, package(path), package(path,replacement) or blankcomment with
+// optional exemptions like blankcomment(embed,tests). It uses the default message of the kind of rule.
+func Parse(in string) (Rule, error) {
+	s := strings.TrimSpace(in)
+	switch strings.ToLower(s) {
+	case string(KindDot):
+		return Rule{Kind: KindDot}.withDefaults(), nil
+	case string(KindBlank):
+		return Rule{Kind: KindBlank}.withDefaults(), nil
+	case string(KindBlankComment):
+		return Rule{Kind: KindBlankComment}.withDefaults(), nil
+	}
+	if strings.HasPrefix(strings.ToLower(s), string(KindBlankComment)+"(") && strings.HasSuffix(s, ")") {
+		r := Rule{Kind: KindBlankComment}
+		for _, exemption := range strings.Split(s[len(KindBlankComment)+1:len(s)-1], ",") {
+			r.Exempt = append(r.Exempt, strings.ToLower(strings.TrimSpace(exemption)))
+		}
+		r = r.withDefaults()
+		return r, r.Validate()
+	}
+	if strings.HasPrefix(strings.ToLower(s), "package(") && strings.HasSuffix(s, ")") {
+		args := strings.Split(s[len("package("):len(s)-1], ",")
+		if len(args) <= 2 {
+			r := Rule{Kind: KindPackage, Path: strings.TrimSpace(args[0])}
+			if len(args) == 2 {
+				r.Replacement = strings.TrimSpace(args[1])
+			}
+			r = r.withDefaults()
+			return r, r.Validate()
+		}
+	}
+	return Rule{}, fmt.Errorf("invalid rule %q, must be dot, blank, package(path), package(path,replacement) or blankcomment(exemptions)", in)
+}
+
+// Validate reports whether the rule is complete.
+func (r Rule) Validate() error {
+	switch r.Kind {
+	case KindDot, KindBlank, KindBlankComment:
+		if r.Path != "" || r.Replacement != "" {
+			return fmt.Errorf("invalid %s rule, path and replacement are only supported by %s rules", r.Kind, KindPackage)
+		}
+	case KindPackage:
+		if r.Path == "" {
+			return fmt.Errorf("invalid %s rule, path must be set", r.Kind)
+		}
+	default:
+		return fmt.Errorf("invalid rule type %q, must be one of %s, %s, %s or %s", r.Kind, KindDot, KindBlank, KindPackage, KindBlankComment)
+	}
+	for _, exemption := range r.Exempt {
+		if r.Kind != KindBlankComment {
+			return fmt.Errorf("invalid %s rule, exemptions are only supported by %s rules", r.Kind, KindBlankComment)
+		}
+		if exemption != ExemptEmbed && exemption != ExemptTests {
+			return fmt.Errorf("invalid exemption %q of %s rule, must be %s or %s", exemption, r.Kind, ExemptEmbed, ExemptTests)
+		}
+	}
+	if r.Message == "" {
+		return fmt.Errorf("invalid %s rule, message must be set", r.Kind)
+	}
+	return nil
+}
+
+// withDefaults sets the message of the rule, if it has none.
+func (r Rule) withDefaults() Rule {
+	if r.Message != "" {
+		return r
+	}
+	switch r.Kind {
+	case KindDot:
+		r.Message = "dot imports are only allowed in tests"
+	case KindBlank:
+		r.Message = "blank imports are only allowed in package main and in files that only import packages and declare init functions"
+	case KindPackage:
+		r.Message = "the package is banned"
+	case KindBlankComment:
+		r.Message = "blank imports need a comment that explains their side effect"
+	}
+	return r
+}
+
+// WithDefaults sets the default message of every rule without one.
+func WithDefaults(rules []Rule) []Rule {
+	if len(rules) == 0 {
+		return nil
+	}
+	out := make([]Rule, len(rules))
+	for i, r := range rules {
+		out[i] = r.withDefaults()
+	}
+	return out
+}
+
+// Violation is an import that breaks a rule.
+type Violation struct {
+	Pos  token.Position
+	Path string
+	Rule Rule
+}
+
+// Message is the message of the rule, together with its replacement.
+func (v Violation) Message() string {
+	if v.Rule.Replacement == "" {
+		return v.Rule.Message
+	}
+	return fmt.Sprintf("%s, use %q instead", v.Rule.Message, v.Rule.Replacement)
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: import %q: %s", v.Pos, v.Path, v.Message())
+}
+
+// Check returns the imports of src that break one of the rules, in the order of the imports. Imports marked with
+// //nolint:gci are skipped.
+func Check(src []byte, filename string, rules []Rule) ([]Violation, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	fileSet := token.NewFileSet()
+	f, err := parse.ParseWithLateImports(fileSet, filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []Violation
+	for _, decl := range f.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			imp := spec.(*ast.ImportSpec)
+			importPath, err := strconv.Unquote(imp.Path.Value)
+			if err != nil || parse.HasNolint(imp.Doc, imp.Comment) {
+				continue
+			}
+			for _, r := range rules {
+				if r.matches(f, filename, genDecl, imp, importPath) {
+					violations = append(violations, Violation{Pos: fileSet.Position(imp.Pos()), Path: importPath, Rule: r})
+				}
+			}
+		}
+	}
+	return violations, nil
+}
+
+func (r Rule) matches(f *ast.File, filename string, decl *ast.GenDecl, imp *ast.ImportSpec, importPath string) bool {
+	switch r.Kind {
+	case KindDot:
+		return imp.Name != nil && imp.Name.Name == "." && !strings.HasSuffix(filename, "_test.go")
+	case KindBlank:
+		// embed and unsafe are imported for //go:embed and //go:linkname directives, which is allowed everywhere
+		if imp.Name == nil || imp.Name.Name != "_" || importPath == "embed" || importPath == "unsafe" {
+			return false
+		}
+		return f.Name.Name != "main" && !onlyInit(f)
+	case KindPackage:
+		return importPath == r.Path || strings.HasPrefix(importPath, r.Path+"/")
+	case KindBlankComment:
+		if imp.Name == nil || imp.Name.Name != "_" || imp.Doc != nil || imp.Comment != nil {
+			return false
+		}
+		// the doc comment of import _ "pkg" without parentheses belongs to the declaration
+		if !decl.Lparen.IsValid() && decl.Doc != nil {
+			return false
+		}
+		if importPath == "embed" && slices.Contains(r.Exempt, ExemptEmbed) {
+			return false
+		}
+		return !(strings.HasSuffix(filename, "_test.go") && slices.Contains(r.Exempt, ExemptTests))
+	}
+	return false
+}
+
+// onlyInit reports whether the file only consists of imports and init functions, like the files that register
+// database drivers.
+func onlyInit(f *ast.File) bool {
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok != token.IMPORT {
+				return false
+			}
+		case *ast.FuncDecl:
+			if d.Name.Name != "init" || d.Recv != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
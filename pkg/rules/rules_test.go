@@ -0,0 +1,195 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected Rule
+	}{
+		{"dot", Rule{Kind: KindDot, Message: "dot imports are only allowed in tests"}},
+		{"Blank", Rule{Kind: KindBlank, Message: "blank imports are only allowed in package main and in files that only import packages and declare init functions"}},
+		{"package(io/ioutil)", Rule{Kind: KindPackage, Path: "io/ioutil", Message: "the package is banned"}},
+		{"package(io/ioutil, os)", Rule{Kind: KindPackage, Path: "io/ioutil", Message: "the package is banned", Replacement: "os"}},
+		{"blankcomment", Rule{Kind: KindBlankComment, Message: "blank imports need a comment that explains their side effect"}},
+		{"blankComment(embed, Tests)", Rule{Kind: KindBlankComment, Message: "blank imports need a comment that explains their side effect", Exempt: []string{"embed", "tests"}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			r, err := Parse(tc.in)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, r)
+		})
+	}
+
+	for _, in := range []string{"", "dots", "package()", "package(a,b,c)", "blankcomment(vendor)", "blankcomment()"} {
+		_, err := Parse(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Rule{Kind: KindPackage, Path: "io/ioutil", Message: "deprecated"}.Validate())
+	assert.Error(t, Rule{Kind: KindPackage, Message: "deprecated"}.Validate())
+	assert.Error(t, Rule{Kind: KindPackage, Path: "io/ioutil"}.Validate())
+	assert.Error(t, Rule{Kind: KindDot, Message: "no", Replacement: "os"}.Validate())
+	assert.Error(t, Rule{Kind: "alias", Message: "no"}.Validate())
+	assert.Error(t, Rule{Kind: KindBlank, Message: "no", Exempt: []string{ExemptTests}}.Validate())
+}
+
+func TestCheck(t *testing.T) {
+	importRules := WithDefaults([]Rule{
+		{Kind: KindDot},
+		{Kind: KindBlank},
+		{Kind: KindPackage, Path: "io/ioutil", Message: "io/ioutil is deprecated", Replacement: "os"},
+		{Kind: KindPackage, Path: "github.com/golang/protobuf"},
+	})
+
+	testCases := []struct {
+		name, filename, src string
+		expected            []string
+	}{
+		{
+			name:     "library",
+			filename: "lib.go",
+			src: `package lib
+
+import (
+	_ "embed"
+	"io/ioutil"
+	. "strings"
+
+	_ "github.com/lib/pq"
+	"github.com/golang/protobuf/proto"
+)
+
+func Lib() {}
+`,
+			expected: []string{
+				`lib.go:5:2: import "io/ioutil": io/ioutil is deprecated, use "os" instead`,
+				`lib.go:6:2: import "strings": dot imports are only allowed in tests`,
+				`lib.go:8:2: import "github.com/lib/pq": blank imports are only allowed in package main and in files that only import packages and declare init functions`,
+				`lib.go:9:2: import "github.com/golang/protobuf/proto": the package is banned`,
+			},
+		},
+		{
+			name:     "test",
+			filename: "lib_test.go",
+			src: `package lib
+
+import . "strings"
+`,
+		},
+		{
+			name:     "main",
+			filename: "main.go",
+			src: `package main
+
+import _ "github.com/lib/pq"
+
+func main() {}
+`,
+		},
+		{
+			name:     "init-only",
+			filename: "drivers.go",
+			src: `package lib
+
+import _ "github.com/lib/pq"
+
+func init() {}
+`,
+		},
+		{
+			name:     "nolint",
+			filename: "lib.go",
+			src: `package lib
+
+import . "strings" //nolint:gci
+`,
+		},
+		{
+			name:     "similar-path",
+			filename: "lib.go",
+			src: `package lib
+
+import "github.com/golang/protobufx"
+`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			violations, err := Check([]byte(tc.src), tc.filename, importRules)
+			require.NoError(t, err)
+			var actual []string
+			for _, v := range violations {
+				actual = append(actual, v.String())
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestCheckBlankComment(t *testing.T) {
+	src := `package lib
+
+import (
+	_ "embed"
+	// registers the postgres driver
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3" // registers the sqlite driver
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// registers the image decoder
+import _ "image/png"
+
+import _ "image/gif"
+`
+	testCases := []struct {
+		name, filename string
+		exempt         []string
+		expected       []string
+	}{
+		{
+			name:     "no-exemptions",
+			filename: "lib.go",
+			expected: []string{
+				`lib.go:4:2: import "embed": blank imports need a comment that explains their side effect`,
+				`lib.go:8:2: import "github.com/go-sql-driver/mysql": blank imports need a comment that explains their side effect`,
+				`lib.go:14:8: import "image/gif": blank imports need a comment that explains their side effect`,
+			},
+		},
+		{
+			name:     "embed",
+			filename: "lib.go",
+			exempt:   []string{ExemptEmbed},
+			expected: []string{
+				`lib.go:8:2: import "github.com/go-sql-driver/mysql": blank imports need a comment that explains their side effect`,
+				`lib.go:14:8: import "image/gif": blank imports need a comment that explains their side effect`,
+			},
+		},
+		{
+			name:     "tests",
+			filename: "lib_test.go",
+			exempt:   []string{ExemptTests},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := WithDefaults([]Rule{{Kind: KindBlankComment, Exempt: tc.exempt}})
+			violations, err := Check([]byte(src), tc.filename, r)
+			require.NoError(t, err)
+			var actual []string
+			for _, v := range violations {
+				actual = append(actual, v.String())
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package section
+
+import (
+	"github.com/daixiang0/gci/pkg/parse"
+	"github.com/daixiang0/gci/pkg/specificity"
+)
+
+// Cgo is the place of the import "C" declarations. They stay declarations of their own, as their doc comment is the
+// cgo preamble, and go in front of the other imports if there is no cgo section.
+type Cgo struct{}
+
+const CgoType = "cgo"
+
+func (c Cgo) MatchSpecificity(spec *parse.GciImports) specificity.MatchSpecificity {
+	if spec.Path == "C" {
+		return specificity.NameMatch{}
+	}
+	return specificity.MisMatch{}
+}
+
+func (c Cgo) String() string {
+	return CgoType
+}
+
+func (c Cgo) Type() string {
+	return CgoType
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 
 	"golang.org/x/mod/modfile"
 
@@ -16,87 +17,296 @@ import (
 
 const LocalModuleType = "localmodule"
 
+// ModuleRule names the reason why a module path is local, or not.
+type ModuleRule string
+
+const (
+	// RuleConfigured is a module path that was set explicitly, e.g. by the analyzer
+	RuleConfigured ModuleRule = "configured"
+	// RuleModule is the module directive of the go.mod file
+	RuleModule ModuleRule = "go.mod module"
+	// RuleWorkspace is a module of a use directive in the go.work file
+	RuleWorkspace ModuleRule = "go.work use"
+	// RuleReplace is a module replaced by a local directory in the go.work or go.mod file
+	RuleReplace ModuleRule = "replace"
+	// RuleVendored is a module listed in vendor/modules.txt, which is never local
+	RuleVendored ModuleRule = "vendor/modules.txt"
+)
+
 type LocalModule struct {
 	Paths []string
+	// Rules holds the rule every path of Paths was found with, paths without rule were configured
+	Rules map[string]ModuleRule
+	// Vendored holds the vendored modules, their packages do not match even if they are below one of Paths
+	Vendored []string
+	// Replace also makes the modules local which are replaced by local directories
+	Replace bool
+	// Scope limits the modules to the module of the file or widens them to its workspace
+	Scope LocalModuleScope
 }
 
+// LocalModuleScope selects which modules are local for a file.
+type LocalModuleScope string
+
+const (
+	// ScopeSelf is only the module the file belongs to, it is more specific than the other scopes
+	ScopeSelf LocalModuleScope = "self"
+	// ScopeWorkspace is every module of the workspace the file belongs to, like the scope without name
+	ScopeWorkspace LocalModuleScope = "workspace"
+)
+
 func (m *LocalModule) MatchSpecificity(spec *parse.GciImports) specificity.MatchSpecificity {
-	for _, path := range m.Paths {
-		if spec.Path == path || strings.HasPrefix(spec.Path, path+"/") {
-			return specificity.LocalModule{}
-		}
+	if rule := m.Rule(spec.Path); rule != "" && rule != RuleVendored {
+		return specificity.LocalModule{Self: m.Scope == ScopeSelf}
 	}
 	return specificity.MisMatch{}
 }
 
+// Rule returns the rule which classifies the package path, RuleVendored if it belongs to a vendored module
+// or an empty rule if it is not local.
+func (m *LocalModule) Rule(pkgPath string) ModuleRule {
+	path := longestModulePath(m.Paths, pkgPath)
+	if vendored := longestModulePath(m.Vendored, pkgPath); len(vendored) > len(path) {
+		return RuleVendored
+	}
+	if path == "" {
+		return ""
+	}
+	if rule, ok := m.Rules[path]; ok {
+		return rule
+	}
+	return RuleConfigured
+}
+
+// longestModulePath returns the longest of the module paths the package path belongs to.
+func longestModulePath(modPaths []string, pkgPath string) string {
+	var longest string
+	for _, path := range modPaths {
+		if (pkgPath == path || strings.HasPrefix(pkgPath, path+"/")) && len(path) > len(longest) {
+			longest = path
+		}
+	}
+	return longest
+}
+
 func (m *LocalModule) String() string {
-	return LocalModuleType
+	var options []string
+	if m.Scope != "" {
+		options = append(options, string(m.Scope))
+	}
+	if m.Replace {
+		options = append(options, "replace")
+	}
+	if len(options) == 0 {
+		return LocalModuleType
+	}
+	return LocalModuleType + "(" + strings.Join(options, ",") + ")"
 }
 
 func (m *LocalModule) Type() string {
 	return LocalModuleType
 }
 
+// Configure sets the module paths if path is given, otherwise they are looked up for every file, see ForFile.
 func (m *LocalModule) Configure(path string) error {
 	if path != "" {
 		m.Paths = []string{path}
-		return nil
+	}
+	return nil
+}
+
+// ForFile returns the section for the file at filePath. Unless the module paths were configured, they are
+// looked up from the directory of the file upwards, so every file uses the module it belongs to.
+func (m *LocalModule) ForFile(filePath string) (*LocalModule, error) {
+	if len(m.Paths) > 0 {
+		return m, nil
 	}
 
-	modPaths, err := m.findLocalModules()
+	fileModule, err := m.findLocalModules(filepath.Dir(filePath))
 	if err != nil {
-		return fmt.Errorf("unable to find local modules: %v", err)
+		return nil, fmt.Errorf("unable to find local modules of %s: %v", filePath, err)
 	}
+	if len(fileModule.Paths) == 0 {
+		// nothing found up to the root, e.g. for generated code outside of the module
+		modPaths, err := m.getModulePathFromEnv()
+		if err != nil {
+			return nil, fmt.Errorf("unable to find local modules of %s: %v", filePath, err)
+		}
+		fileModule.add(RuleModule, modPaths...)
+	}
+	if len(fileModule.Paths) == 0 {
+		return nil, fmt.Errorf("could not find module path for `localModule` configuration of %s", filePath)
+	}
+	return fileModule, nil
+}
 
-	if len(modPaths) == 0 {
-		return errors.New("could not find module path for `localModule` configuration")
+// add adds the module paths which are not vendored and not yet covered by another path.
+func (m *LocalModule) add(rule ModuleRule, modPaths ...string) {
+	if m.Rules == nil {
+		m.Rules = map[string]ModuleRule{}
+	}
+	for _, path := range modPaths {
+		if slices.Contains(m.Vendored, path) {
+			continue
+		}
+		if _, ok := m.Rules[path]; !ok {
+			m.Rules[path] = rule
+		}
 	}
+	m.Paths = m.removeRedundantModulePaths(append(m.Paths, modPaths...))
+	m.Paths = slices.DeleteFunc(m.Paths, func(path string) bool {
+		return slices.Contains(m.Vendored, path)
+	})
+}
 
-	m.Paths = modPaths
-	return nil
+// moduleRoots holds the nearest go.mod and go.work files of a directory.
+type moduleRoots struct {
+	module, moduleDir                    string
+	moduleReplaces, moduleVendored       []string
+	workspace                            []string
+	workspaceDir                         string
+	workspaceReplaces, workspaceVendored []string
+	hasModule, hasWorkspace              bool
 }
 
-func (m *LocalModule) findLocalModules() ([]string, error) {
-	modsPath, err := m.getModulesPathFromWorkspace()
+// localModule returns the modules of the workspace, unless the module is nested in the workspace directory without
+// being part of the workspace. Like for the go command, go.work takes precedence over go.mod in the same directory.
+// ScopeSelf only returns the module of the file.
+func (r moduleRoots) localModule(scope LocalModuleScope, replace bool) *LocalModule {
+	result := &LocalModule{Replace: replace, Scope: scope}
+	if r.hasWorkspace {
+		nested := r.hasModule && strings.HasPrefix(r.moduleDir, r.workspaceDir+string(filepath.Separator))
+		if !nested || longestModulePath(r.workspace, r.module) != "" {
+			result.Vendored = r.workspaceVendored
+			if scope != ScopeSelf {
+				result.add(RuleWorkspace, r.workspace...)
+				result.add(RuleReplace, r.workspaceReplaces...)
+			} else if r.hasModule {
+				result.add(RuleModule, r.module)
+			}
+			if r.hasModule {
+				result.add(RuleReplace, r.moduleReplaces...)
+			}
+			return result
+		}
+	}
+	if r.hasModule {
+		result.Vendored = r.moduleVendored
+		result.add(RuleModule, r.module)
+		result.add(RuleReplace, r.moduleReplaces...)
+	}
+	return result
+}
+
+// moduleRootsCache caches the module roots of every directory, so go.mod and go.work files are read only once per run.
+var moduleRootsCache = rootsCache{dirs: map[rootsKey]moduleRoots{}}
+
+type rootsKey struct {
+	dir     string
+	replace bool
+}
+
+type rootsCache struct {
+	lock sync.Mutex
+	dirs map[rootsKey]moduleRoots
+}
+
+func (c *rootsCache) lookup(m *LocalModule, dir string) (moduleRoots, error) {
+	key := rootsKey{dir, m.Replace}
+	c.lock.Lock()
+	roots, ok := c.dirs[key]
+	c.lock.Unlock()
+	if ok {
+		return roots, nil
+	}
+
+	if parent := filepath.Dir(dir); parent != dir {
+		var err error
+		if roots, err = c.lookup(m, parent); err != nil {
+			return moduleRoots{}, err
+		}
+	}
+
+	modsPath, err := m.getModulesPathFromWorkspace(dir)
 	switch {
 	case err != nil && !errors.Is(err, os.ErrNotExist):
-		return nil, err
+		return moduleRoots{}, err
 	case err == nil:
-		return modsPath, nil
+		roots.workspace, roots.workspaceDir, roots.hasWorkspace = modsPath, dir, true
+		if roots.workspaceVendored, err = m.getVendoredModules(dir); err != nil {
+			return moduleRoots{}, err
+		}
+		roots.workspaceReplaces = nil
+		if m.Replace {
+			if roots.workspaceReplaces, err = m.getReplacedModulePaths(filepath.Join(dir, "go.work")); err != nil {
+				return moduleRoots{}, err
+			}
+		}
 	}
 
-	modPath, err := m.getModulePathFromRootMod()
+	modPath, err := m.getModulePathFromRootMod(dir)
 	switch {
 	case err != nil && !errors.Is(err, os.ErrNotExist):
-		return nil, err
+		return moduleRoots{}, err
 	case err == nil:
-		return []string{modPath}, nil
+		roots.module, roots.moduleDir, roots.hasModule = modPath, dir, true
+		if roots.moduleVendored, err = m.getVendoredModules(dir); err != nil {
+			return moduleRoots{}, err
+		}
+		roots.moduleReplaces = nil
+		if m.Replace {
+			if roots.moduleReplaces, err = m.getReplacedModulePaths(filepath.Join(dir, "go.mod")); err != nil {
+				return moduleRoots{}, err
+			}
+		}
+	}
+
+	c.lock.Lock()
+	c.dirs[key] = roots
+	c.lock.Unlock()
+	return roots, nil
+}
+
+// findLocalModules returns the modules of the nearest go.work or go.mod file in dir or one of its parents.
+func (m *LocalModule) findLocalModules(dir string) (*LocalModule, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	roots, err := moduleRootsCache.lookup(m, dir)
+	if err != nil {
+		return nil, err
 	}
+	return roots.localModule(m.Scope, m.Replace), nil
+}
 
-	return nil, nil
+func (m *LocalModule) getModulePathFromRootMod(dir string) (string, error) {
+	return m.getModulePath(filepath.Join(dir, "go.mod"))
 }
 
-func (m *LocalModule) getModulePathFromRootMod() (string, error) {
-	modFilePath := "go.mod"
-	if v, exists := os.LookupEnv("GOMOD"); exists {
-		modFilePath = v
+// getModulePathFromEnv returns the module of the go.mod file set by the go command in GOMOD, if any.
+func (m *LocalModule) getModulePathFromEnv() ([]string, error) {
+	modFilePath := os.Getenv("GOMOD")
+	if modFilePath == "" || modFilePath == os.DevNull {
+		return nil, nil
 	}
 
 	modPath, err := m.getModulePath(modFilePath)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return modPath, nil
+	return []string{modPath}, nil
 }
 
-func (m *LocalModule) getModulesPathFromWorkspace() ([]string, error) {
-	rawWorkFile, err := os.ReadFile("go.work")
+func (m *LocalModule) getModulesPathFromWorkspace(dir string) ([]string, error) {
+	workFilePath := filepath.Join(dir, "go.work")
+	rawWorkFile, err := os.ReadFile(workFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read go.work file: %w", err)
 	}
 
-	workFile, err := modfile.ParseWork("go.work", rawWorkFile, nil)
+	workFile, err := modfile.ParseWork(workFilePath, rawWorkFile, nil)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse go.work file: %v", err)
 	}
@@ -105,6 +315,9 @@ func (m *LocalModule) getModulesPathFromWorkspace() ([]string, error) {
 
 	for _, use := range workFile.Use {
 		modFilePath := filepath.Join(use.Path, "go.mod")
+		if !filepath.IsAbs(modFilePath) {
+			modFilePath = filepath.Join(dir, modFilePath)
+		}
 		modPath, err := m.getModulePath(modFilePath)
 		if err != nil {
 			return nil, fmt.Errorf("unable to get mod file %s defined go.work: %v", modFilePath, err)
@@ -116,6 +329,59 @@ func (m *LocalModule) getModulesPathFromWorkspace() ([]string, error) {
 	return m.removeRedundantModulePaths(modsPath), nil
 }
 
+// getReplacedModulePaths returns the modules which the go.mod or go.work file replaces by local directories.
+func (m *LocalModule) getReplacedModulePaths(filePath string) ([]string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", filePath, err)
+	}
+
+	var replaces []*modfile.Replace
+	if filepath.Base(filePath) == "go.work" {
+		workFile, err := modfile.ParseWork(filePath, data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse go.work file: %v", err)
+		}
+		replaces = workFile.Replace
+	} else {
+		modFile, err := modfile.Parse(filePath, data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s mod file: %v", filePath, err)
+		}
+		replaces = modFile.Replace
+	}
+
+	var modPaths []string
+	for _, replace := range replaces {
+		// a replacement by another module version is not local
+		if replace.New.Version == "" && modfile.IsDirectoryPath(replace.New.Path) {
+			modPaths = append(modPaths, replace.Old.Path)
+		}
+	}
+	return modPaths, nil
+}
+
+// getVendoredModules returns the modules listed in vendor/modules.txt in dir, if any.
+func (m *LocalModule) getVendoredModules(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "vendor", "modules.txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read vendor/modules.txt: %w", err)
+	}
+
+	var modPaths []string
+	for _, line := range strings.Split(string(data), "\n") {
+		// modules are listed as "# path version [=> replacement]", their packages follow on their own lines
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "#" {
+			modPaths = append(modPaths, fields[1])
+		}
+	}
+	return modPaths, nil
+}
+
 func (m *LocalModule) getModulePath(modFilePath string) (string, error) {
 	rawModFile, err := os.ReadFile(modFilePath)
 	if err != nil {
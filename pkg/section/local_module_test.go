@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/daixiang0/gci/pkg/parse"
 	"github.com/daixiang0/gci/pkg/specificity"
 )
 
@@ -57,9 +58,17 @@ func TestLocalModule_findLocalModules(t *testing.T) {
 			testdataDir:          "both_files",
 			expectedModulesPaths: []string{"fake.tld/example/work"},
 		},
-		"empty directory": {
+		"empty directory - module of the parent directory": {
 			testdataDir:          "empty_dir",
-			expectedModulesPaths: []string{},
+			expectedModulesPaths: []string{"github.com/daixiang0/gci"},
+		},
+		"workspace module": {
+			testdataDir:          filepath.Join("work_simple", "module1"),
+			expectedModulesPaths: []string{"fake.tld/example/module1", "fake.tld/example/module2"},
+		},
+		"module nested in a workspace without being used": {
+			testdataDir:          filepath.Join("work_nested_module", "independent"),
+			expectedModulesPaths: []string{"fake.tld/example/independent"},
 		},
 		"redundant paths deduplication": {
 			testdataDir:          "work_redundant_paths",
@@ -75,54 +84,175 @@ func TestLocalModule_findLocalModules(t *testing.T) {
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
-			t.Chdir(filepath.Join(testdata, tt.testdataDir))
-
-			modPaths, err := m.findLocalModules()
+			found, err := m.findLocalModules(filepath.Join(testdata, tt.testdataDir))
 			if tt.expectFailure {
 				assert.NotNil(t, err)
-			} else {
-				assert.NoError(t, err)
+				assert.Nil(t, found)
+				return
 			}
-			assert.ElementsMatch(t, tt.expectedModulesPaths, modPaths)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expectedModulesPaths, found.Paths)
 		})
 	}
 }
 
+func TestLocalModule_scope(t *testing.T) {
+	dir := filepath.Join("./testdata", "local_module", "work_simple", "module1")
+
+	for scope, expected := range map[LocalModuleScope][]string{
+		"":             {"fake.tld/example/module1", "fake.tld/example/module2"},
+		ScopeWorkspace: {"fake.tld/example/module1", "fake.tld/example/module2"},
+		ScopeSelf:      {"fake.tld/example/module1"},
+	} {
+		found, err := (&LocalModule{Scope: scope}).findLocalModules(dir)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, expected, found.Paths, scope)
+		assert.Equal(t, scope, found.Scope)
+	}
+
+	self := &LocalModule{Paths: []string{"fake.tld/example/module1"}, Scope: ScopeSelf}
+	workspace := &LocalModule{Paths: []string{"fake.tld/example/module1", "fake.tld/example/module2"}, Scope: ScopeWorkspace}
+	spec := &parse.GciImports{Path: "fake.tld/example/module1/foo"}
+	assert.True(t, self.MatchSpecificity(spec).IsMoreSpecific(workspace.MatchSpecificity(spec)))
+	assert.Equal(t, "localmodule(self)", self.String())
+	assert.Equal(t, "localmodule(workspace,replace)", (&LocalModule{Scope: ScopeWorkspace, Replace: true}).String())
+}
+
+func TestLocalModule_replaceAndVendor(t *testing.T) {
+	testdata := filepath.Join("./testdata", "local_module")
+
+	t.Run("without replace", func(t *testing.T) {
+		found, err := new(LocalModule).findLocalModules(filepath.Join(testdata, "mod_replace"))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"fake.tld/example/app"}, found.Paths)
+	})
+
+	t.Run("go.mod replace", func(t *testing.T) {
+		found, err := (&LocalModule{Replace: true}).findLocalModules(filepath.Join(testdata, "mod_replace"))
+		assert.NoError(t, err)
+		// the vendored and the remote replacement are not local
+		assert.ElementsMatch(t, []string{"fake.tld/example/app", "fake.tld/example/lib"}, found.Paths)
+
+		for pkgPath, rule := range map[string]ModuleRule{
+			"fake.tld/example/app/internal":  RuleModule,
+			"fake.tld/example/lib/foo":       RuleReplace,
+			"fake.tld/example/app/tools/gen": RuleVendored,
+			"fake.tld/example/vendoredlib":   RuleVendored,
+			"fake.tld/remote":                "",
+		} {
+			assert.Equal(t, rule, found.Rule(pkgPath), pkgPath)
+		}
+		assert.Equal(t, specificity.MisMatch{}, found.MatchSpecificity(&parse.GciImports{Path: "fake.tld/example/app/tools/gen"}))
+		assert.Equal(t, specificity.LocalModule{}, found.MatchSpecificity(&parse.GciImports{Path: "fake.tld/example/lib"}))
+	})
+
+	t.Run("go.work and go.mod replace", func(t *testing.T) {
+		found, err := (&LocalModule{Replace: true}).findLocalModules(filepath.Join(testdata, "work_replace", "module1"))
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"fake.tld/example/module1", "fake.tld/example/shared", "fake.tld/example/tools"}, found.Paths)
+		assert.Equal(t, RuleWorkspace, found.Rule("fake.tld/example/module1"))
+		assert.Equal(t, RuleReplace, found.Rule("fake.tld/example/shared/x"))
+	})
+
+	t.Run("configured path", func(t *testing.T) {
+		m := new(LocalModule)
+		assert.NoError(t, m.Configure("fake.tld/configured"))
+		assert.Equal(t, RuleConfigured, m.Rule("fake.tld/configured/pkg"))
+	})
+}
+
 func TestLocalModule_getModulePathFromRootMod(t *testing.T) {
 	m := new(LocalModule)
 	testdata := filepath.Join("./testdata", "local_module")
 
 	t.Run("non existing file", func(t *testing.T) {
-		t.Chdir(filepath.Join(testdata, "empty_dir"))
-
-		modPath, err := m.getModulePathFromRootMod()
+		modPath, err := m.getModulePathFromRootMod(filepath.Join(testdata, "empty_dir"))
 		assert.ErrorIs(t, err, os.ErrNotExist)
 		assert.Empty(t, modPath)
 	})
 
 	t.Run("invalid mod file", func(t *testing.T) {
-		t.Chdir(filepath.Join(testdata, "mod_malformed"))
-
-		modPath, err := m.getModulePathFromRootMod()
+		modPath, err := m.getModulePathFromRootMod(filepath.Join(testdata, "mod_malformed"))
 		assert.ErrorContains(t, err, "no module path found")
 		assert.NotErrorIs(t, err, os.ErrNotExist)
 		assert.Empty(t, modPath)
 	})
 
 	t.Run("mod path found", func(t *testing.T) {
-		t.Chdir(filepath.Join(testdata, "mod_simple"))
-
-		modPath, err := m.getModulePathFromRootMod()
+		modPath, err := m.getModulePathFromRootMod(filepath.Join(testdata, "mod_simple"))
 		assert.NoError(t, err)
 		assert.Equal(t, "fake.tld/example/simple", modPath)
 	})
+}
+
+func TestLocalModule_getModulePathFromEnv(t *testing.T) {
+	m := new(LocalModule)
 
 	t.Run("mod path found with GOMOD env", func(t *testing.T) {
-		t.Setenv("GOMOD", filepath.Join(testdata, "mod_simple", "go.mod"))
+		t.Setenv("GOMOD", filepath.Join("./testdata", "local_module", "mod_simple", "go.mod"))
 
-		modPath, err := m.getModulePathFromRootMod()
+		modPaths, err := m.getModulePathFromEnv()
 		assert.NoError(t, err)
-		assert.Equal(t, "fake.tld/example/simple", modPath)
+		assert.Equal(t, []string{"fake.tld/example/simple"}, modPaths)
+	})
+
+	t.Run("outside of a module", func(t *testing.T) {
+		t.Setenv("GOMOD", os.DevNull)
+
+		modPaths, err := m.getModulePathFromEnv()
+		assert.NoError(t, err)
+		assert.Empty(t, modPaths)
+	})
+}
+
+func TestLocalModule_ForFile(t *testing.T) {
+	testdata, err := filepath.Abs(filepath.Join("./testdata", "local_module"))
+	assert.NoError(t, err)
+
+	t.Run("configured paths", func(t *testing.T) {
+		m := &LocalModule{Paths: []string{"example.com/configured"}}
+
+		fileModule, err := m.ForFile(filepath.Join(testdata, "mod_simple", "main.go"))
+		assert.NoError(t, err)
+		assert.Same(t, m, fileModule)
+	})
+
+	t.Run("nearest module of the file", func(t *testing.T) {
+		// the working directory does not belong to any of the modules
+		t.Chdir(t.TempDir())
+
+		for file, expected := range map[string][]string{
+			filepath.Join(testdata, "mod_simple", "main.go"):                         {"fake.tld/example/simple"},
+			filepath.Join(testdata, "mod_simple", "pkg", "sub", "lib.go"):            {"fake.tld/example/simple"},
+			filepath.Join(testdata, "work_simple", "module1", "main.go"):             {"fake.tld/example/module1", "fake.tld/example/module2"},
+			filepath.Join(testdata, "work_redundant_paths", "foo", "bar", "main.go"): {"fake.tld/example/foo/bar", "fake.tld/other/project"},
+		} {
+			fileModule, err := new(LocalModule).ForFile(file)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, expected, fileModule.Paths, file)
+		}
+	})
+
+	t.Run("cached per directory", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/first\n"), 0o644))
+
+		fileModule, err := new(LocalModule).ForFile(filepath.Join(dir, "a.go"))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"example.com/first"}, fileModule.Paths)
+
+		// changes are not noticed within a single run
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/second\n"), 0o644))
+		fileModule, err = new(LocalModule).ForFile(filepath.Join(dir, "b.go"))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"example.com/first"}, fileModule.Paths)
+	})
+
+	t.Run("no module", func(t *testing.T) {
+		t.Setenv("GOMOD", "")
+
+		_, err := new(LocalModule).ForFile(filepath.Join(t.TempDir(), "main.go"))
+		assert.ErrorContains(t, err, "could not find module path for `localModule` configuration")
 	})
 }
 
@@ -131,35 +261,27 @@ func TestLocalModule_getModulePathFromWorkspace(t *testing.T) {
 	testdata := filepath.Join("./testdata", "local_module")
 
 	t.Run("non existing file", func(t *testing.T) {
-		t.Chdir(filepath.Join(testdata, "empty_dir"))
-
-		modsPath, err := m.getModulesPathFromWorkspace()
+		modsPath, err := m.getModulesPathFromWorkspace(filepath.Join(testdata, "empty_dir"))
 		assert.ErrorIs(t, err, os.ErrNotExist)
 		assert.Empty(t, modsPath)
 	})
 
 	t.Run("invalid work file", func(t *testing.T) {
-		t.Chdir(filepath.Join(testdata, "work_malformed"))
-
-		modsPath, err := m.getModulesPathFromWorkspace()
+		modsPath, err := m.getModulesPathFromWorkspace(filepath.Join(testdata, "work_malformed"))
 		assert.ErrorContains(t, err, "unable to parse go.work file")
 		assert.NotErrorIs(t, err, os.ErrNotExist)
 		assert.Empty(t, modsPath)
 	})
 
 	t.Run("invalid use attributes", func(t *testing.T) {
-		t.Chdir(filepath.Join(testdata, "work_missing_referenced_mod"))
-
-		modsPath, err := m.getModulesPathFromWorkspace()
+		modsPath, err := m.getModulesPathFromWorkspace(filepath.Join(testdata, "work_missing_referenced_mod"))
 		assert.ErrorContains(t, err, "unable to get mod file")
 		assert.NotErrorIs(t, err, os.ErrNotExist)
 		assert.Empty(t, modsPath)
 	})
 
 	t.Run("work file found and valid", func(t *testing.T) {
-		t.Chdir(filepath.Join(testdata, "work_simple"))
-
-		modsPath, err := m.getModulesPathFromWorkspace()
+		modsPath, err := m.getModulesPathFromWorkspace(filepath.Join(testdata, "work_simple"))
 		assert.NoError(t, err)
 		assert.Equal(t, []string{"fake.tld/example/module1", "fake.tld/example/module2"}, modsPath)
 	})
@@ -0,0 +1,93 @@
+package section
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/modfile"
+)
+
+// RequiredModules returns the path of the module the file at filePath belongs to, followed by the modules its go.mod
+// requires. It is empty outside of a module.
+func RequiredModules(filePath string) ([]string, error) {
+	dir, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return nil, err
+	}
+	return requiredModules.lookup(dir)
+}
+
+// ModuleRoot returns the module of modules the import path belongs to, the longest one if modules are nested. Paths
+// outside of the modules belong to their host, like go.uber.org, or on hosts with a module per repository to their
+// repository, like github.com/owner/repo.
+func ModuleRoot(modules []string, importPath string) string {
+	if root := longestModulePath(modules, importPath); root != "" {
+		return root
+	}
+	return pathRoot(importPath)
+}
+
+// repositoryHosts serve the modules of their repositories at host/owner/repo.
+var repositoryHosts = map[string]bool{
+	"bitbucket.org": true,
+	"github.com":    true,
+	"gitlab.com":    true,
+}
+
+// pathRoot returns the repository of importPath on the repositoryHosts, otherwise its first element.
+func pathRoot(importPath string) string {
+	elems := strings.Split(importPath, "/")
+	if repositoryHosts[elems[0]] && len(elems) >= 3 {
+		return strings.Join(elems[:3], "/")
+	}
+	return elems[0]
+}
+
+// requiredModules caches the modules of the go.mod of every directory.
+var requiredModules = requiredModulesCache{dirs: map[string][]string{}}
+
+type requiredModulesCache struct {
+	lock sync.Mutex
+	dirs map[string][]string
+}
+
+func (c *requiredModulesCache) lookup(dir string) ([]string, error) {
+	c.lock.Lock()
+	modules, ok := c.dirs[dir]
+	c.lock.Unlock()
+	if ok {
+		return modules, nil
+	}
+
+	modFilePath := filepath.Join(dir, "go.mod")
+	rawModFile, err := os.ReadFile(modFilePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if parent := filepath.Dir(dir); parent != dir {
+			if modules, err = c.lookup(parent); err != nil {
+				return nil, err
+			}
+		}
+	case err != nil:
+		return nil, err
+	default:
+		modFile, err := modfile.ParseLax(modFilePath, rawModFile, nil)
+		if err != nil {
+			return nil, err
+		}
+		if modFile.Module != nil {
+			modules = append(modules, modFile.Module.Mod.Path)
+		}
+		for _, r := range modFile.Require {
+			modules = append(modules, r.Mod.Path)
+		}
+	}
+
+	c.lock.Lock()
+	c.dirs[dir] = modules
+	c.lock.Unlock()
+	return modules, nil
+}
@@ -23,6 +23,8 @@ func Parse(data []string) (SectionList, error) {
 			list = append(list, Default{})
 		} else if s == "standard" {
 			list = append(list, Standard{})
+		} else if s == "standard(gomod)" {
+			list = append(list, Standard{ByGoVersion: true})
 		} else if s == "newline" {
 			list = append(list, NewLine{})
 		} else if strings.HasPrefix(s, "prefix(") && len(d) > 8 {
@@ -33,11 +35,19 @@ func Parse(data []string) (SectionList, error) {
 			list = append(list, Dot{})
 		} else if s == "blank" {
 			list = append(list, Blank{})
+		} else if s == "cgo" {
+			list = append(list, Cgo{})
 		} else if s == "alias" {
 			list = append(list, Alias{})
 		} else if s == "localmodule" {
 			// pointer because we need to mutate the section at configuration time
 			list = append(list, &LocalModule{})
+		} else if strings.HasPrefix(s, "localmodule(") && strings.HasSuffix(s, ")") {
+			m, err := parseLocalModule(s[len("localmodule(") : len(s)-1])
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, m)
 		} else {
 			errString += fmt.Sprintf(" %s", s)
 		}
@@ -47,3 +57,22 @@ func Parse(data []string) (SectionList, error) {
 	}
 	return list, nil
 }
+
+// parseLocalModule parses the comma separated options of a localmodule section.
+func parseLocalModule(options string) (*LocalModule, error) {
+	m := &LocalModule{}
+	for _, option := range strings.Split(options, ",") {
+		switch option = strings.TrimSpace(option); option {
+		case "replace":
+			m.Replace = true
+		case string(ScopeSelf), string(ScopeWorkspace):
+			if m.Scope != "" {
+				return nil, fmt.Errorf("invalid localmodule options %q, only one of self and workspace is allowed", options)
+			}
+			m.Scope = LocalModuleScope(option)
+		default:
+			return nil, fmt.Errorf("invalid localmodule option %q, must be self, workspace or replace", option)
+		}
+	}
+	return m, nil
+}
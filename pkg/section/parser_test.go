@@ -35,6 +35,11 @@ func TestParse(t *testing.T) {
 			expectedSection: SectionList{Custom{"go-UPPER-case"}},
 			expectedError:   nil,
 		},
+		{
+			input:           []string{"standard", "cgo", "default"},
+			expectedSection: SectionList{Standard{}, Cgo{}, Default{}},
+			expectedError:   nil,
+		},
 		{
 			input:           []string{"prefix("},
 			expectedSection: nil,
@@ -45,6 +50,31 @@ func TestParse(t *testing.T) {
 			expectedSection: SectionList{Custom{"domainA,domainB"}},
 			expectedError:   nil,
 		},
+		{
+			input:           []string{"standard(gomod)"},
+			expectedSection: SectionList{Standard{ByGoVersion: true}},
+			expectedError:   nil,
+		},
+		{
+			input:           []string{"localmodule(replace)"},
+			expectedSection: SectionList{&LocalModule{Replace: true}},
+			expectedError:   nil,
+		},
+		{
+			input:           []string{"localmodule(self)", "LocalModule(workspace, replace)"},
+			expectedSection: SectionList{&LocalModule{Scope: ScopeSelf}, &LocalModule{Scope: ScopeWorkspace, Replace: true}},
+			expectedError:   nil,
+		},
+		{
+			input:           []string{"localmodule(self,workspace)"},
+			expectedSection: nil,
+			expectedError:   errors.New(`invalid localmodule options "self,workspace", only one of self and workspace is allowed`),
+		},
+		{
+			input:           []string{"localmodule(vendor)"},
+			expectedSection: nil,
+			expectedError:   errors.New(`invalid localmodule option "vendor", must be self, workspace or replace`),
+		},
 	}
 	for _, test := range testCases {
 		parsedSection, err := Parse(test.input)
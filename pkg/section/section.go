@@ -1,6 +1,8 @@
 package section
 
 import (
+	"slices"
+
 	"github.com/daixiang0/gci/pkg/parse"
 	"github.com/daixiang0/gci/pkg/specificity"
 )
@@ -34,3 +36,38 @@ func DefaultSections() SectionList {
 func DefaultSectionSeparators() SectionList {
 	return SectionList{NewLine{}}
 }
+
+// ForFile returns the sections to format the file at path with, see LocalModule.ForFile and Standard.ForFile.
+func (list SectionList) ForFile(path string) (SectionList, error) {
+	var result SectionList
+	for i, s := range list {
+		var fileSection Section
+		switch s := s.(type) {
+		case *LocalModule:
+			fileModule, err := s.ForFile(path)
+			if err != nil {
+				return nil, err
+			}
+			fileSection = fileModule
+		case Standard:
+			if !s.ByGoVersion {
+				continue
+			}
+			fileStandard, err := s.ForFile(path)
+			if err != nil {
+				return nil, err
+			}
+			fileSection = fileStandard
+		default:
+			continue
+		}
+		if result == nil {
+			result = slices.Clone(list)
+		}
+		result[i] = fileSection
+	}
+	if result == nil {
+		return list, nil
+	}
+	return result, nil
+}
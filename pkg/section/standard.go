@@ -1,22 +1,45 @@
 package section
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/modfile"
+
 	"github.com/daixiang0/gci/pkg/parse"
 	"github.com/daixiang0/gci/pkg/specificity"
 )
 
 const StandardType = "standard"
 
-type Standard struct{}
+type Standard struct {
+	// ByGoVersion classifies imports against the standard library of the go directive in the go.mod of the file
+	ByGoVersion bool
+	// GoVersion is the go directive the file was resolved with, see ForFile
+	GoVersion string
+}
 
 func (s Standard) MatchSpecificity(spec *parse.GciImports) specificity.MatchSpecificity {
-	if isStandard(spec.Path) {
+	standard := isStandard(spec.Path)
+	if s.ByGoVersion {
+		standard = isStandardInVersion(spec.Path, s.GoVersion)
+	}
+	if standard {
 		return specificity.StandardMatch{}
 	}
 	return specificity.MisMatch{}
 }
 
 func (s Standard) String() string {
+	if s.ByGoVersion {
+		return StandardType + "(gomod)"
+	}
 	return StandardType
 }
 
@@ -24,7 +47,157 @@ func (s Standard) Type() string {
 	return StandardType
 }
 
+// ForFile returns the section for the file at filePath. With ByGoVersion, it holds the go directive of the nearest
+// go.mod of the file. Files without go.mod or go directive use the newest known standard library.
+func (s Standard) ForFile(filePath string) (Standard, error) {
+	if !s.ByGoVersion || s.GoVersion != "" {
+		return s, nil
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return s, err
+	}
+	s.GoVersion, err = goVersions.lookup(dir)
+	if err != nil {
+		return s, fmt.Errorf("unable to find go version of %s: %v", filePath, err)
+	}
+	return s, nil
+}
+
 func isStandard(pkg string) bool {
 	_, ok := standardPackages[pkg]
 	return ok
 }
+
+// isStandardInVersion reports whether pkg is part of the standard library of the Go version, e.g. 1.21 or 1.21.3.
+// Versions newer than the generated list are checked against GOROOT/src, if there is a local toolchain.
+func isStandardInVersion(pkg, version string) bool {
+	if minor, ok := goMinorVersion(version); ok && minor <= newestStandardVersion {
+		since, ok := standardPackageVersions[pkg]
+		return ok && since <= minor
+	}
+	if goroot := localGoroot(); goroot != "" {
+		return goroot.hasPackage(pkg)
+	}
+	_, ok := standardPackageVersions[pkg]
+	return ok
+}
+
+// goMinorVersion returns the minor version of a Go 1 version like 1.21, 1.21.3 or 1.21rc1.
+func goMinorVersion(version string) (int, bool) {
+	rest, ok := strings.CutPrefix(version, "1.")
+	if !ok {
+		return 0, false
+	}
+	end := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+	if end >= 0 {
+		rest = rest[:end]
+	}
+	minor, err := strconv.Atoi(rest)
+	return minor, err == nil
+}
+
+// goVersions caches the go directive of every directory.
+var goVersions = goVersionCache{dirs: map[string]string{}}
+
+type goVersionCache struct {
+	lock sync.Mutex
+	dirs map[string]string
+}
+
+func (c *goVersionCache) lookup(dir string) (string, error) {
+	c.lock.Lock()
+	version, ok := c.dirs[dir]
+	c.lock.Unlock()
+	if ok {
+		return version, nil
+	}
+
+	modFilePath := filepath.Join(dir, "go.mod")
+	rawModFile, err := os.ReadFile(modFilePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if parent := filepath.Dir(dir); parent != dir {
+			if version, err = c.lookup(parent); err != nil {
+				return "", err
+			}
+		}
+	case err != nil:
+		return "", err
+	default:
+		modFile, err := modfile.ParseLax(modFilePath, rawModFile, nil)
+		if err != nil {
+			return "", err
+		}
+		if modFile.Go != nil {
+			version = modFile.Go.Version
+		}
+	}
+
+	c.lock.Lock()
+	c.dirs[dir] = version
+	c.lock.Unlock()
+	return version, nil
+}
+
+// goroot is the GOROOT of a local toolchain, whose src directory holds the standard library.
+type goroot string
+
+var (
+	gorootOnce     sync.Once
+	gorootDir      goroot
+	gorootPackages sync.Map
+)
+
+// localGoroot returns GOROOT, or an empty string if there is no local toolchain.
+func localGoroot() goroot {
+	gorootOnce.Do(func() {
+		dir := os.Getenv("GOROOT")
+		if dir == "" {
+			out, err := exec.Command("go", "env", "GOROOT").Output()
+			if err != nil {
+				return
+			}
+			dir = strings.TrimSpace(string(out))
+		}
+		if info, err := os.Stat(filepath.Join(dir, "src")); err == nil && info.IsDir() {
+			gorootDir = goroot(dir)
+		}
+	})
+	return gorootDir
+}
+
+// hasPackage reports whether GOROOT/src holds Go files in the directory of pkg, which user code can import.
+func (g goroot) hasPackage(pkg string) bool {
+	if found, ok := gorootPackages.Load(pkg); ok {
+		return found.(bool)
+	}
+
+	found := false
+	// the go command, internal and vendored packages can not be imported by user code
+	if importable(pkg) {
+		entries, _ := os.ReadDir(filepath.Join(string(g), "src", filepath.FromSlash(pkg)))
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".go") && !strings.HasSuffix(entry.Name(), "_test.go") {
+				found = true
+				break
+			}
+		}
+	}
+	gorootPackages.Store(pkg, found)
+	return found
+}
+
+func importable(pkg string) bool {
+	if strings.HasPrefix(pkg, ".") {
+		return false
+	}
+	for _, elem := range strings.Split(pkg, "/") {
+		if elem == "internal" {
+			return false
+		}
+	}
+	first, _, _ := strings.Cut(pkg, "/")
+	return first != "cmd" && first != "vendor"
+}
@@ -1,8 +1,13 @@
 package section
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
+	"github.com/daixiang0/gci/pkg/parse"
 	"github.com/daixiang0/gci/pkg/specificity"
 )
 
@@ -19,3 +24,64 @@ func TestStandardPackageSpecificity(t *testing.T) {
 	}
 	testSpecificity(t, testCases)
 }
+
+func TestStandardPackageSpecificityByGoVersion(t *testing.T) {
+	testCases := []specificityTestData{
+		{"slices", Standard{ByGoVersion: true, GoVersion: "1.21"}, specificity.StandardMatch{}},
+		{"slices", Standard{ByGoVersion: true, GoVersion: "1.20.5"}, specificity.MisMatch{}},
+		{"slices", Standard{ByGoVersion: true, GoVersion: "1.21rc2"}, specificity.StandardMatch{}},
+		{"iter", Standard{ByGoVersion: true, GoVersion: "1.22"}, specificity.MisMatch{}},
+		{"iter", Standard{ByGoVersion: true, GoVersion: "1.23"}, specificity.StandardMatch{}},
+		{"fmt", Standard{ByGoVersion: true, GoVersion: "1.0"}, specificity.StandardMatch{}},
+		{"unsafe", Standard{ByGoVersion: true, GoVersion: "1.16"}, specificity.StandardMatch{}},
+		// added without exported API, so the api files do not tell their first release
+		{"runtime/cgo", Standard{ByGoVersion: true, GoVersion: "1.0"}, specificity.StandardMatch{}},
+		{"runtime/race", Standard{ByGoVersion: true, GoVersion: "1.0"}, specificity.MisMatch{}},
+		{"runtime/race", Standard{ByGoVersion: true, GoVersion: "1.1"}, specificity.StandardMatch{}},
+		{"syscall/js", Standard{ByGoVersion: true, GoVersion: "1.10"}, specificity.MisMatch{}},
+		{"syscall/js", Standard{ByGoVersion: true, GoVersion: "1.11"}, specificity.StandardMatch{}},
+		{"time/tzdata", Standard{ByGoVersion: true, GoVersion: "1.14"}, specificity.MisMatch{}},
+		{"time/tzdata", Standard{ByGoVersion: true, GoVersion: "1.15"}, specificity.StandardMatch{}},
+		// experiments are no part of a released standard library
+		{"arena", Standard{ByGoVersion: true, GoVersion: "1.21"}, specificity.MisMatch{}},
+		{"arena", Standard{}, specificity.StandardMatch{}},
+		{"github.com/foo/bar", Standard{ByGoVersion: true, GoVersion: "1.21"}, specificity.MisMatch{}},
+	}
+	testSpecificity(t, testCases)
+}
+
+func TestStandardForFile(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "old", "pkg"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "old", "go.mod"), []byte("module example.com/old\n\ngo 1.20\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/new\n\ngo 1.23.1\n"), 0o644))
+
+	s, err := Standard{ByGoVersion: true}.ForFile(filepath.Join(dir, "old", "pkg", "main.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, "1.20", s.GoVersion)
+	assert.Equal(t, specificity.MisMatch{}, s.MatchSpecificity(&parse.GciImports{Path: "slices"}))
+
+	s, err = Standard{ByGoVersion: true}.ForFile(filepath.Join(dir, "main.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, "1.23.1", s.GoVersion)
+	assert.Equal(t, specificity.StandardMatch{}, s.MatchSpecificity(&parse.GciImports{Path: "slices"}))
+
+	// without the mode the go.mod is not read
+	s, err = Standard{}.ForFile(filepath.Join(dir, "main.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, Standard{}, s)
+}
+
+func TestStandardNewerThanList(t *testing.T) {
+	goroot := localGoroot()
+	if goroot == "" {
+		t.Skip("no local toolchain")
+	}
+	newer := Standard{ByGoVersion: true, GoVersion: "1.999"}
+	assert.Equal(t, specificity.StandardMatch{}, newer.MatchSpecificity(&parse.GciImports{Path: "net/http"}))
+	assert.Equal(t, specificity.MisMatch{}, newer.MatchSpecificity(&parse.GciImports{Path: "cmd/go"}))
+	assert.Equal(t, specificity.MisMatch{}, newer.MatchSpecificity(&parse.GciImports{Path: "internal/abi"}))
+	assert.Equal(t, specificity.MisMatch{}, newer.MatchSpecificity(&parse.GciImports{Path: "crypto/internal/fips140"}))
+	assert.Equal(t, specificity.MisMatch{}, newer.MatchSpecificity(&parse.GciImports{Path: "vendor/golang.org/x/net/dns/dnsmessage"}))
+	assert.Equal(t, specificity.MisMatch{}, newer.MatchSpecificity(&parse.GciImports{Path: "example.com/foo"}))
+}
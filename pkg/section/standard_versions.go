@@ -0,0 +1,191 @@
+package section
+
+// Code generated based on the api files of go1.27.1. DO NOT EDIT.
+
+// newestStandardVersion is the newest Go 1 minor version of standardPackageVersions.
+const newestStandardVersion = 27
+
+// standardPackageVersions holds the Go 1 minor version every standard package was added in.
+var standardPackageVersions = map[string]int{
+	"archive/tar":            0,
+	"archive/zip":            0,
+	"bufio":                  0,
+	"bytes":                  0,
+	"cmp":                    21,
+	"compress/bzip2":         0,
+	"compress/flate":         0,
+	"compress/gzip":          0,
+	"compress/lzw":           0,
+	"compress/zlib":          0,
+	"container/heap":         0,
+	"container/list":         0,
+	"container/ring":         0,
+	"context":                7,
+	"crypto":                 0,
+	"crypto/aes":             0,
+	"crypto/cipher":          0,
+	"crypto/des":             0,
+	"crypto/dsa":             0,
+	"crypto/ecdh":            20,
+	"crypto/ecdsa":           0,
+	"crypto/ed25519":         13,
+	"crypto/elliptic":        0,
+	"crypto/fips140":         24,
+	"crypto/hkdf":            24,
+	"crypto/hmac":            0,
+	"crypto/hpke":            26,
+	"crypto/md5":             0,
+	"crypto/mldsa":           27,
+	"crypto/mlkem":           24,
+	"crypto/mlkem/mlkemtest": 26,
+	"crypto/pbkdf2":          24,
+	"crypto/rand":            0,
+	"crypto/rc4":             0,
+	"crypto/rsa":             0,
+	"crypto/sha1":            0,
+	"crypto/sha256":          0,
+	"crypto/sha3":            24,
+	"crypto/sha512":          0,
+	"crypto/subtle":          0,
+	"crypto/tls":             0,
+	"crypto/x509":            0,
+	"crypto/x509/pkix":       0,
+	"database/sql":           0,
+	"database/sql/driver":    0,
+	"debug/buildinfo":        18,
+	"debug/dwarf":            0,
+	"debug/elf":              0,
+	"debug/gosym":            0,
+	"debug/macho":            0,
+	"debug/pe":               0,
+	"debug/plan9obj":         3,
+	"embed":                  16,
+	"encoding":               2,
+	"encoding/ascii85":       0,
+	"encoding/asn1":          0,
+	"encoding/base32":        0,
+	"encoding/base64":        0,
+	"encoding/binary":        0,
+	"encoding/csv":           0,
+	"encoding/gob":           0,
+	"encoding/hex":           0,
+	"encoding/json":          0,
+	"encoding/json/jsontext": 27,
+	"encoding/json/v2":       27,
+	"encoding/pem":           0,
+	"encoding/xml":           0,
+	"errors":                 0,
+	"expvar":                 0,
+	"flag":                   0,
+	"fmt":                    0,
+	"go/ast":                 0,
+	"go/build":               0,
+	"go/build/constraint":    16,
+	"go/constant":            5,
+	"go/doc":                 0,
+	"go/doc/comment":         19,
+	"go/format":              1,
+	"go/importer":            5,
+	"go/parser":              0,
+	"go/printer":             0,
+	"go/scanner":             0,
+	"go/token":               0,
+	"go/types":               5,
+	"go/version":             22,
+	"hash":                   0,
+	"hash/adler32":           0,
+	"hash/crc32":             0,
+	"hash/crc64":             0,
+	"hash/fnv":               0,
+	"hash/maphash":           14,
+	"html":                   0,
+	"html/template":          0,
+	"image":                  0,
+	"image/color":            0,
+	"image/color/palette":    2,
+	"image/draw":             0,
+	"image/gif":              0,
+	"image/jpeg":             0,
+	"image/png":              0,
+	"index/suffixarray":      0,
+	"io":                     0,
+	"io/fs":                  16,
+	"io/ioutil":              0,
+	"iter":                   23,
+	"log":                    0,
+	"log/slog":               21,
+	"log/syslog":             0,
+	"maps":                   21,
+	"math":                   0,
+	"math/big":               0,
+	"math/bits":              9,
+	"math/cmplx":             0,
+	"math/rand":              0,
+	"math/rand/v2":           22,
+	"mime":                   0,
+	"mime/multipart":         0,
+	"mime/quotedprintable":   5,
+	"net":                    0,
+	"net/http":               0,
+	"net/http/cgi":           0,
+	"net/http/cookiejar":     1,
+	"net/http/fcgi":          0,
+	"net/http/httptest":      0,
+	"net/http/httptrace":     7,
+	"net/http/httputil":      0,
+	"net/http/pprof":         0,
+	"net/mail":               0,
+	"net/netip":              18,
+	"net/rpc":                0,
+	"net/rpc/jsonrpc":        0,
+	"net/smtp":               0,
+	"net/textproto":          0,
+	"net/url":                0,
+	"os":                     0,
+	"os/exec":                0,
+	"os/signal":              0,
+	"os/user":                0,
+	"path":                   0,
+	"path/filepath":          0,
+	"plugin":                 8,
+	"reflect":                0,
+	"regexp":                 0,
+	"regexp/syntax":          0,
+	"runtime":                0,
+	"runtime/cgo":            0,
+	"runtime/coverage":       20,
+	"runtime/debug":          0,
+	"runtime/metrics":        16,
+	"runtime/pprof":          0,
+	"runtime/race":           1,
+	"runtime/trace":          5,
+	"slices":                 21,
+	"sort":                   0,
+	"strconv":                0,
+	"strings":                0,
+	"structs":                23,
+	"sync":                   0,
+	"sync/atomic":            0,
+	"syscall":                0,
+	"syscall/js":             11,
+	"testing":                0,
+	"testing/cryptotest":     26,
+	"testing/fstest":         16,
+	"testing/iotest":         0,
+	"testing/quick":          0,
+	"testing/slogtest":       21,
+	"testing/synctest":       25,
+	"text/scanner":           0,
+	"text/tabwriter":         0,
+	"text/template":          0,
+	"text/template/parse":    0,
+	"time":                   0,
+	"time/tzdata":            15,
+	"unicode":                0,
+	"unicode/utf16":          0,
+	"unicode/utf8":           0,
+	"unique":                 23,
+	"unsafe":                 0,
+	"uuid":                   27,
+	"weak":                   24,
+}
@@ -0,0 +1,16 @@
+module fake.tld/example/app
+
+go 1.21
+
+require (
+	fake.tld/example/lib v0.0.0
+	fake.tld/example/vendoredlib v1.0.0
+	fake.tld/example/app/tools v1.2.0
+	fake.tld/remote v1.0.0
+)
+
+replace fake.tld/example/lib => ../lib
+
+replace fake.tld/example/vendoredlib => ./third_party/vendoredlib
+
+replace fake.tld/remote => fake.tld/fork v1.0.0
@@ -0,0 +1,3 @@
+module fake.tld/example/independent
+
+go 1.21
@@ -0,0 +1,3 @@
+module fake.tld/example/used
+
+go 1.21
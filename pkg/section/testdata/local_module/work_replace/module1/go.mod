@@ -0,0 +1,5 @@
+module fake.tld/example/module1
+
+go 1.21
+
+replace fake.tld/example/tools => ../tools
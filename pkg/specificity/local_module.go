@@ -1,9 +1,13 @@
 package specificity
 
-type LocalModule struct{}
+// LocalModule is a match of a local module, Self is a match of the module of the file itself.
+type LocalModule struct {
+	Self bool
+}
 
 func (m LocalModule) IsMoreSpecific(than MatchSpecificity) bool {
-	return isMoreSpecific(m, than)
+	otherLocalModule, isLocalModule := than.(LocalModule)
+	return isMoreSpecific(m, than) || (isLocalModule && m.Self && !otherLocalModule.Self)
 }
 
 func (m LocalModule) Equal(to MatchSpecificity) bool {
@@ -25,5 +25,5 @@ func TestSpecificityEquality(t *testing.T) {
 }
 
 func testCasesInSpecificityOrder() []MatchSpecificity {
-	return []MatchSpecificity{MisMatch{}, Default{}, StandardMatch{}, Match{0}, Match{1}}
+	return []MatchSpecificity{MisMatch{}, Default{}, StandardMatch{}, Match{0}, Match{1}, LocalModule{}, LocalModule{Self: true}}
 }
@@ -0,0 +1,63 @@
+package gci
+
+import (
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+
+	"github.com/daixiang0/gci/v2/pkg/cache"
+	"github.com/daixiang0/gci/v2/pkg/config"
+	"github.com/daixiang0/gci/v2/pkg/gci"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manages the cache of already formatted files",
+	Long:  `Files which are already formatted are remembered by their content, the configuration and the version of gci, so later runs can skip them. Pass --no-cache to the formatting commands to bypass the cache.`,
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Removes all cached results",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := cache.DefaultDir()
+		if err != nil {
+			return err
+		}
+		return cache.Clean(dir)
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheCleanCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+// version identifies the build, including the commit of development builds.
+func version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	v := info.Main.Version
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" || setting.Key == "vcs.modified" {
+			v += " " + setting.Key + "=" + setting.Value
+		}
+	}
+	return v
+}
+
+// openCache returns the cache for cfg, or nil if it is not available.
+func openCache(cfg config.Config) *cache.Cache {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return nil
+	}
+	c, err := cache.Open(dir, gci.CacheKey(cfg, version()))
+	if err != nil {
+		return nil
+	}
+	return c
+}
@@ -1,24 +1,48 @@
 package gci
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
 
 	"github.com/daixiang0/gci/v2/pkg/gci"
 )
 
+var (
+	diffContext    int
+	diffColor      string
+	diffSideBySide bool
+)
+
 var diffCmd = &cobra.Command{
 	Use:   "diff path...",
 	Short: "Diff prints a patch in the style of the diff tool",
 	Long:  `Diff prints a patch in the style of the diff tool that contains the required changes to the file to make it adhere to the specified formatting.`,
-	Args:  cobra.MinimumNArgs(1),
+	Args:  stdinPathArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := parseSections(); err != nil {
 			return err
 		}
-		return gci.DiffFormattedFiles(args, cfg)
+		args, err := readFileLists(args)
+		if err != nil {
+			return err
+		}
+		colorMode, err := gci.ParseColorMode(diffColor)
+		if err != nil {
+			return err
+		}
+		parseKeepGoing(cmd, false)
+		return reportFileErrors(cmd, gci.DiffFormattedFilesWithOptions(args, cfg, gci.DiffOptions{
+			Context:    diffContext,
+			Color:      colorMode.Enabled(os.Stdout),
+			SideBySide: diffSideBySide,
+		}))
 	},
 }
 
 func init() {
+	diffCmd.Flags().IntVarP(&diffContext, "context", "U", gci.DefaultDiffContext, "Number of unchanged lines shown around every change")
+	diffCmd.Flags().StringVar(&diffColor, "color", string(gci.ColorAuto), "Colorize the diff: auto, always or never. auto only colors terminal output and honours NO_COLOR")
+	diffCmd.Flags().BoolVar(&diffSideBySide, "side-by-side", false, "Show the original and the formatted import block in two columns, labeled with their sections")
 	rootCmd.AddCommand(diffCmd)
 }
@@ -10,12 +10,17 @@ var listCmd = &cobra.Command{
 	Use:   "list path...",
 	Short: "Prints the filenames that need to be formatted",
 	Long:  `Prints the filenames that need to be formatted. If you want to show the diff use diff instead, and if you want to apply the changes use write instead`,
-	Args:  cobra.MinimumNArgs(1),
+	Args:  pathArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := parseSections(); err != nil {
 			return err
 		}
-		return gci.ListUnFormattedFiles(args, cfg)
+		args, err := readFileLists(args)
+		if err != nil {
+			return err
+		}
+		parseKeepGoing(cmd, true)
+		return reportFileErrors(cmd, gci.ListUnFormattedFiles(args, cfg))
 	},
 }
 
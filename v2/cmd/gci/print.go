@@ -10,12 +10,17 @@ var printCmd = &cobra.Command{
 	Use:   "print path...",
 	Short: "Print outputs the formatted file",
 	Long:  `Print outputs the formatted file. If you want to apply the changes to a file use write instead!`,
-	Args:  cobra.MinimumNArgs(1),
+	Args:  stdinPathArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := parseSections(); err != nil {
 			return err
 		}
-		return gci.PrintFormattedFiles(args, cfg)
+		args, err := readFileLists(args)
+		if err != nil {
+			return err
+		}
+		parseKeepGoing(cmd, false)
+		return reportFileErrors(cmd, gci.PrintFormattedFiles(args, cfg))
 	},
 }
 
@@ -3,17 +3,30 @@ package gci
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/daixiang0/gci/v2/pkg/config"
+	"github.com/daixiang0/gci/v2/pkg/gci"
+	"github.com/daixiang0/gci/v2/pkg/parse"
 	"github.com/daixiang0/gci/v2/pkg/section"
 )
 
 var (
-	cfg       config.Config
-	sections  []string
-	debugMode bool
+	cfg            config.Config
+	sections       []string
+	lineEndings    string
+	generatedFiles string
+	debugMode      bool
+	keepGoing      bool
+	failFast       bool
+	noCache        bool
+	filesFrom      string
+	aliasPairs     []string
+	sortMode       string
+	sectionSort    []string
+	subgroups      []string
 )
 
 var rootCmd = &cobra.Command{
@@ -24,7 +37,10 @@ var rootCmd = &cobra.Command{
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		// failed files have already been reported in a summary
+		if len(gci.FileErrors(err)) == 0 {
+			fmt.Println(err)
+		}
 		os.Exit(1)
 	}
 }
@@ -33,8 +49,93 @@ func init() {
 	rootCmd.PersistentFlags().StringArrayVarP(&sections, "section", "s", []string{"standard", "default"}, "Sections define how imports will be processed")
 	rootCmd.PersistentFlags().BoolVarP(&debugMode, "debug", "d", false, "Enables debug output")
 	rootCmd.PersistentFlags().BoolVar(&cfg.SkipGenerated, "skip-generated", false, "Skip generated files")
+	rootCmd.PersistentFlags().StringVar(&generatedFiles, "generated-files", string(config.GeneratedFilesLax), "How --skip-generated detects generated files: strict only skips files with a // Code generated ... DO NOT EDIT. comment before the package clause, lax also files mentioning markers like \"do not edit\" anywhere")
 	rootCmd.PersistentFlags().BoolVar(&cfg.SkipVendor, "skip-vendor", false, "Skip files inside vendor directory")
 	rootCmd.PersistentFlags().BoolVar(&cfg.CustomOrder, "custom-order", false, "Enable custom order of sections")
+	rootCmd.PersistentFlags().BoolVar(&cfg.Dedupe, "dedupe", false, "Remove duplicate imports and aliases that equal the package name")
+	rootCmd.PersistentFlags().BoolVar(&cfg.NoMerge, "no-merge", false, "Keep separate import declarations instead of merging them into one")
+	rootCmd.PersistentFlags().BoolVar(&cfg.FormatEmbedded, "embedded", false, "Also format Go code blocks in Markdown files and .go files in txtar archives")
+	rootCmd.PersistentFlags().IntVarP(&cfg.Jobs, "jobs", "j", 0, "Number of files processed in parallel, 0 uses GOMAXPROCS")
+	rootCmd.PersistentFlags().BoolVar(&keepGoing, "keep-going", false, "Process all files even if some of them fail and report every failure at the end, the default for list")
+	rootCmd.PersistentFlags().BoolVar(&failFast, "fail-fast", false, "Stop at the first file that fails, the default for print, write and diff")
+	rootCmd.MarkFlagsMutuallyExclusive("keep-going", "fail-fast")
+	rootCmd.PersistentFlags().StringVar(&filesFrom, "files-from", "", "Also process the paths listed in this file, separated by newlines or NUL bytes. - or a - argument reads the list from STDIN")
+	rootCmd.PersistentFlags().StringVar(&cfg.StdinFilename, "stdin-filename", "", "Path of the file whose content is read from STDIN, used for module detection, --skip-vendor and diff headers")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Do not skip files which were already formatted in a previous run")
+	rootCmd.PersistentFlags().StringVar(&sortMode, "sort", string(parse.SortLexical), "Order of the imports within a section: lexical, natural, case-insensitive or by-alias")
+	rootCmd.PersistentFlags().StringArrayVar(&sectionSort, "section-sort", nil, "Order of the imports within one section as section=mode, overrides --sort")
+	rootCmd.PersistentFlags().StringArrayVar(&subgroups, "subgroup-by-module", nil, "Section whose imports are separated by blank lines into the modules required by the go.mod of the file")
+	rootCmd.PersistentFlags().StringArrayVar(&aliasPairs, "alias", nil, "Canonical alias of an import path as path=alias, other names of the import are renamed together with their selectors")
+	rootCmd.PersistentFlags().StringVar(&lineEndings, "line-endings", string(config.LineEndingsPreserve), "Line endings of the formatted files: preserve, lf or crlf")
+}
+
+// parseKeepGoing decides whether cmd processes all files before reporting errors.
+// byDefault applies unless --keep-going or --fail-fast is given.
+func parseKeepGoing(cmd *cobra.Command, byDefault bool) {
+	cfg.KeepGoing = byDefault
+	if cmd.Flags().Changed("keep-going") {
+		cfg.KeepGoing = keepGoing
+	}
+	if cmd.Flags().Changed("fail-fast") {
+		cfg.KeepGoing = !failFast
+	}
+}
+
+// reportFileErrors prints a summary of the failed files, which replaces the usual error output.
+func reportFileErrors(cmd *cobra.Command, err error) error {
+	if gci.PrintErrorSummary(os.Stderr, err) {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+	}
+	return err
+}
+
+// pathArgs requires at least one path unless the paths are listed in a file.
+func pathArgs(cmd *cobra.Command, args []string) error {
+	if filesFrom != "" {
+		return nil
+	}
+	return cobra.MinimumNArgs(1)(cmd, args)
+}
+
+// stdinPathArgs also accepts no paths if the content of a file is read from STDIN.
+func stdinPathArgs(cmd *cobra.Command, args []string) error {
+	if cfg.StdinFilename != "" {
+		return nil
+	}
+	return pathArgs(cmd, args)
+}
+
+// readFileLists replaces a - argument with the paths listed on STDIN and adds the paths listed in the --files-from file.
+func readFileLists(args []string) ([]string, error) {
+	var paths, lists []string
+	for _, arg := range args {
+		if arg == "-" {
+			lists = append(lists, arg)
+		} else {
+			paths = append(paths, arg)
+		}
+	}
+	if filesFrom != "" {
+		lists = append(lists, filesFrom)
+	}
+
+	stdinRead := false
+	for _, list := range lists {
+		if list == "-" {
+			if stdinRead {
+				continue
+			}
+			stdinRead = true
+			cfg.NoStdin = true
+		}
+		listed, err := gci.ReadFileList(list)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, gci.FilterFileList(listed, cfg)...)
+	}
+	return paths, nil
 }
 
 func parseSections() error {
@@ -46,5 +147,51 @@ func parseSections() error {
 		parsedSections = section.DefaultSections()
 	}
 	cfg.Sections = parsedSections
+	cfg.Debug = debugMode
+
+	if cfg.Jobs < 0 {
+		return fmt.Errorf("invalid number of jobs %d: must not be negative", cfg.Jobs)
+	}
+
+	cfg.LineEndings, err = config.ParseLineEndings(lineEndings)
+	if err != nil {
+		return err
+	}
+
+	cfg.GeneratedFiles, err = config.ParseGeneratedFiles(generatedFiles)
+	if err != nil {
+		return err
+	}
+
+	cfg.Aliases, err = config.ParseAliases(aliasPairs)
+	if err != nil {
+		return err
+	}
+
+	cfg.Sort, err = parse.ParseSortMode(sortMode)
+	if err != nil {
+		return err
+	}
+	modes := make(map[string]string, len(sectionSort))
+	for _, pair := range sectionSort {
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 {
+			return fmt.Errorf("invalid section sort mode %q, must be section=mode", pair)
+		}
+		modes[pair[:i]] = pair[i+1:]
+	}
+	cfg.SectionSort, err = config.ParseSectionSort(cfg.Sections, modes)
+	if err != nil {
+		return err
+	}
+
+	cfg.SubgroupByModule, err = config.ParseSubgroupByModule(cfg.Sections, subgroups)
+	if err != nil {
+		return err
+	}
+
+	if !noCache {
+		cfg.Cache = openCache(cfg)
+	}
 	return nil
 }
@@ -10,12 +10,17 @@ var writeCmd = &cobra.Command{
 	Use:   "write path...",
 	Short: "Write modifies the specified files in-place",
 	Long:  `Write modifies the specified files in-place`,
-	Args:  cobra.MinimumNArgs(1),
+	Args:  pathArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := parseSections(); err != nil {
 			return err
 		}
-		return gci.WriteFormattedFiles(args, cfg)
+		args, err := readFileLists(args)
+		if err != nil {
+			return err
+		}
+		parseKeepGoing(cmd, false)
+		return reportFileErrors(cmd, gci.WriteFormattedFiles(args, cfg))
 	},
 }
 
@@ -13,8 +13,6 @@ import (
 	"strconv"
 	"strings"
 
-	"golang.org/x/tools/go/ast/astutil"
-
 	"github.com/daixiang0/gci/v2/pkg/config"
 )
 
@@ -29,6 +27,9 @@ type Options struct {
 	TabWidth  int
 
 	FormatOnly bool
+
+	// Modules are the module roots of the sections grouped by module, see section.RequiredModules
+	Modules []string
 }
 
 func Process(filename string, src []byte, opt *Options) (formatted []byte, err error) {
@@ -43,19 +44,27 @@ func Process(filename string, src []byte, opt *Options) (formatted []byte, err e
 
 func formatFile(fset *token.FileSet, file *ast.File, src []byte, adjust func(orig []byte, src []byte) []byte, opt *Options) ([]byte, error) {
 	moveCgoDeclsToTop(file)
-	mergeImports(file)
-	sortImports(opt.Config, fset.File(file.Pos()), file)
+	sortImports(opt.Config, opt.Modules, fset.File(file.Pos()), file)
 
 	var spacesBefore []string
-	for _, impSection := range astutil.Imports(fset, file) {
+	// every import declaration is split into sections on its own, comments between the imports do not end a section
+	for _, decl := range file.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.IMPORT {
+			continue
+		}
 		lastGroup := -1
-		for _, importSpec := range impSection {
+		lastRoot := ""
+		for _, spec := range decl.Specs {
+			importSpec := spec.(*ast.ImportSpec)
 			importPath, _ := strconv.Unquote(importSpec.Path.Value)
 			groupNum := importGroup(opt.Config, importPath, importSpec)
-			if groupNum != lastGroup && lastGroup != -1 {
+			root := importRoot(opt.Config, opt.Modules, groupNum, importPath)
+			if lastGroup != -1 && (groupNum != lastGroup || root != lastRoot) {
 				spacesBefore = append(spacesBefore, importPath)
 			}
 			lastGroup = groupNum
+			lastRoot = root
 		}
 	}
 
@@ -85,7 +94,7 @@ func formatFile(fset *token.FileSet, file *ast.File, src []byte, adjust func(ori
 	out = normalizeImportDeclSpacing(out)
 	out = restoreInlineImportCommentSpacing(src, out)
 	out = normalizeInlineImportCommentSpacing(src, out)
-	out = fixImportCommentLayout(opt.Config, src, out)
+	out = fixImportCommentLayout(opt.Config, opt.Modules, src, out)
 	out = normalizeCgoCommentIndentation(out)
 
 	if opt.FormatOnly {
@@ -205,7 +214,7 @@ func restoreInlineImportCommentSpacing(src []byte, out []byte) []byte {
 	return bytes.Join(lines, []byte("\n"))
 }
 
-func fixImportCommentLayout(cfg *config.Config, src []byte, out []byte) []byte {
+func fixImportCommentLayout(cfg *config.Config, modules []string, src []byte, out []byte) []byte {
 	docPaths := extractDocImportPaths(src)
 	if len(docPaths) == 0 {
 		return out
@@ -214,6 +223,7 @@ func fixImportCommentLayout(cfg *config.Config, src []byte, out []byte) []byte {
 	lines := bytes.Split(out, []byte("\n"))
 	inImports := false
 	lastGroup := -1
+	lastRoot := ""
 	for i := 0; i < len(lines); i++ {
 		trimmed := bytes.TrimSpace(lines[i])
 		if !inImports && bytes.HasPrefix(trimmed, []byte("import")) {
@@ -232,7 +242,9 @@ func fixImportCommentLayout(cfg *config.Config, src []byte, out []byte) []byte {
 		}
 		path := m[1]
 		group := importGroup(cfg, path, buildImportSpecFromLine(lines[i], path))
-		needsBreak := lastGroup != -1 && group != lastGroup
+		root := importRoot(cfg, modules, group, path)
+		needsBreak := lastGroup != -1 && (group != lastGroup || root != lastRoot)
+		lastRoot = root
 		if _, ok := docPaths[path]; !ok {
 			lastGroup = group
 			continue
@@ -3,17 +3,17 @@ package imports
 import (
 	"go/ast"
 	"go/token"
-	"log"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/daixiang0/gci/v2/pkg/config"
 	gciParse "github.com/daixiang0/gci/v2/pkg/parse"
+	"github.com/daixiang0/gci/v2/pkg/section"
 	"github.com/daixiang0/gci/v2/pkg/specificity"
 )
 
-func sortImports(cfg *config.Config, tokFile *token.File, f *ast.File) {
+func sortImports(cfg *config.Config, modules []string, tokFile *token.File, f *ast.File) {
 	for i, d := range f.Decls {
 		d, ok := d.(*ast.GenDecl)
 		if !ok || d.Tok != token.IMPORT {
@@ -45,7 +45,7 @@ func sortImports(cfg *config.Config, tokFile *token.File, f *ast.File) {
 		}
 
 		// Sort all specs together based on section configuration
-		d.Specs = sortSpecs(cfg, tokFile, f, d.Specs)
+		d.Specs = sortSpecs(cfg, modules, tokFile, f, d.Specs)
 
 		if len(d.Specs) > 0 {
 			lastSpec := d.Specs[len(d.Specs)-1]
@@ -57,32 +57,6 @@ func sortImports(cfg *config.Config, tokFile *token.File, f *ast.File) {
 	}
 }
 
-func mergeImports(f *ast.File) {
-	if len(f.Decls) <= 1 {
-		return
-	}
-
-	var first *ast.GenDecl
-	for i := 0; i < len(f.Decls); i++ {
-		decl := f.Decls[i]
-		gen, ok := decl.(*ast.GenDecl)
-		if !ok || gen.Tok != token.IMPORT || declImports(gen, "C") {
-			continue
-		}
-		if first == nil {
-			first = gen
-			continue
-		}
-		first.Lparen = first.Pos()
-		for _, spec := range gen.Specs {
-			spec.(*ast.ImportSpec).Path.ValuePos = first.Pos()
-			first.Specs = append(first.Specs, spec)
-		}
-		f.Decls = append(f.Decls[:i], f.Decls[i+1:]...)
-		i--
-	}
-}
-
 func moveCgoDeclsToTop(f *ast.File) {
 	if len(f.Decls) == 0 {
 		return
@@ -165,21 +139,21 @@ type posSpan struct {
 	End   token.Pos
 }
 
-func sortSpecs(cfg *config.Config, tokFile *token.File, f *ast.File, specs []ast.Spec) []ast.Spec {
+func sortSpecs(cfg *config.Config, modules []string, tokFile *token.File, f *ast.File, specs []ast.Spec) []ast.Spec {
 	if len(specs) <= 1 {
 		return specs
 	}
 
-	pos := make([]posSpan, len(specs))
-	for i, s := range specs {
-		pos[i] = posSpan{s.Pos(), s.End()}
+	// the doc comment of the first import belongs to it as much as the ones of the other imports
+	start := specs[0].Pos()
+	if doc := specs[0].(*ast.ImportSpec).Doc; doc != nil {
+		start = doc.Pos()
 	}
-
-	lastLine := tokFile.Line(pos[len(pos)-1].End)
+	lastLine := tokFile.Line(specs[len(specs)-1].End())
 	cstart := len(f.Comments)
 	cend := len(f.Comments)
 	for i, g := range f.Comments {
-		if g.Pos() < pos[0].Start {
+		if g.Pos() < start {
 			continue
 		}
 		if i < cstart {
@@ -225,14 +199,34 @@ func sortSpecs(cfg *config.Config, tokFile *token.File, f *ast.File, specs []ast
 		comments = f.Comments[cstart : cstart+len(expandedComments)]
 	}
 
+	// every import and comment line of the block is a slot, which the sorted imports and their comments are put on
+	slotLines := map[int]struct{}{}
+	for _, s := range specs {
+		slotLines[tokFile.Line(s.Pos())] = struct{}{}
+	}
+	for _, g := range comments {
+		for _, c := range g.List {
+			slotLines[tokFile.Line(c.Slash)] = struct{}{}
+		}
+	}
+	slots := make([]int, 0, len(slotLines))
+	for line := range slotLines {
+		slots = append(slots, line)
+	}
+	sort.Ints(slots)
+	slotStarts := make([]token.Pos, len(slots))
+	slotEnds := make([]token.Pos, len(slots))
+	for i, line := range slots {
+		slotStarts[i] = tokFile.LineStart(line)
+		slotEnds[i] = token.Pos(tokFile.Base() + tokFile.Size())
+		if line < tokFile.LineCount() {
+			slotEnds[i] = tokFile.LineStart(line+1) - 1
+		}
+	}
+
+	// comments on the line of an import stay behind it, the others are the doc of the next import
 	docGroups := map[*ast.ImportSpec][]*ast.CommentGroup{}
 	trailingGroups := map[*ast.ImportSpec][]*ast.CommentGroup{}
-	type commentGroupOffset struct {
-		offsets   []int
-		columns   []int
-		maxOffset int
-	}
-	commentOffsets := map[*ast.CommentGroup]commentGroupOffset{}
 	for _, g := range comments {
 		cLine := tokFile.Line(g.Pos())
 		bestIdx := len(specs) - 1
@@ -248,31 +242,6 @@ func sortSpecs(cfg *config.Config, tokFile *token.File, f *ast.File, specs []ast
 			}
 		}
 		s := specs[bestIdx].(*ast.ImportSpec)
-		lines := make([]int, len(g.List))
-		columns := make([]int, len(g.List))
-		minLine := int(^uint(0) >> 1)
-		for i, c := range g.List {
-			pos := tokFile.Position(c.Slash)
-			lines[i] = pos.Line
-			columns[i] = pos.Column
-			if pos.Line < minLine {
-				minLine = pos.Line
-			}
-		}
-		offsets := make([]int, len(lines))
-		maxOffset := 0
-		for i, line := range lines {
-			offset := line - minLine
-			offsets[i] = offset
-			if offset > maxOffset {
-				maxOffset = offset
-			}
-		}
-		commentOffsets[g] = commentGroupOffset{
-			offsets:   offsets,
-			columns:   columns,
-			maxOffset: maxOffset,
-		}
 		if cLine < tokFile.Line(s.Pos()) {
 			docGroups[s] = append(docGroups[s], g)
 		} else {
@@ -280,105 +249,77 @@ func sortSpecs(cfg *config.Config, tokFile *token.File, f *ast.File, specs []ast
 		}
 	}
 
-	sort.Sort(byImportSpec{cfg, specs})
+	sort.Sort(byImportSpec{cfg, modules, specs})
 
 	deduped := specs[:0]
 	for i, s := range specs {
 		if i == len(specs)-1 || !collapse(s.(*ast.ImportSpec), specs[i+1].(*ast.ImportSpec)) {
 			deduped = append(deduped, s)
-		} else {
-			p := s.Pos()
-			tokFile.MergeLine(tokFile.Line(p))
 		}
 	}
 	specs = deduped
 
-	for i, s := range specs {
+	// the position of the i-th item on slot line, before the end of that line so it stays on it
+	next := 0
+	slotPos := func(slot, i int) token.Pos {
+		slot = min(slot, len(slots)-1)
+		return min(slotStarts[slot]+token.Pos(i), slotEnds[slot])
+	}
+	var used []token.Pos
+	for _, s := range specs {
 		s := s.(*ast.ImportSpec)
-		if i == 0 && len(docGroups[s]) > 0 {
-			maxOffset := 0
-			for _, g := range docGroups[s] {
-				if meta := commentOffsets[g]; meta.maxOffset > maxOffset {
-					maxOffset = meta.maxOffset
-				}
-			}
-			shift := maxOffset + 1
-			startLine := tokFile.Line(pos[i].Start)
-			startOffset := pos[i].Start - tokFile.LineStart(startLine)
-			pos[i].Start = tokFile.LineStart(startLine+shift) + startOffset
-			endLine := tokFile.Line(pos[i].End)
-			endOffset := pos[i].End - tokFile.LineStart(endLine)
-			pos[i].End = tokFile.LineStart(endLine+shift) + endOffset
-		}
-		if s.Name != nil {
-			s.Name.NamePos = pos[i].Start
-		}
-		s.Path.ValuePos = pos[i].Start
-		s.EndPos = pos[i].End
-
-		importLine := tokFile.Line(pos[i].Start)
 		for _, g := range docGroups[s] {
-			meta := commentOffsets[g]
-			startLine := importLine - 1 - meta.maxOffset
-			if i > 0 {
-				prevLine := tokFile.Line(pos[i-1].End)
-				if startLine < prevLine+1 {
-					startLine = prevLine + 1
+			first := tokFile.Line(g.Pos())
+			lines := 0
+			var prev token.Pos
+			for _, c := range g.List {
+				offset := tokFile.Line(c.Slash) - first
+				// comments next to each other keep their order on the line
+				onLine := 0
+				if prev.IsValid() && tokFile.Line(prev)-first == offset {
+					onLine = int(c.Slash - tokFile.LineStart(tokFile.Line(c.Slash)))
 				}
+				prev = c.Slash
+				c.Slash = slotPos(next+offset, onLine)
+				lines = offset + 1
 			}
-			if i == 0 {
-				startLine = importLine - meta.maxOffset
-			}
-			if startLine < 1 {
-				startLine = 1
-			}
-			for j, c := range g.List {
-				line := startLine + meta.offsets[j]
-				if line < 1 {
-					line = 1
-				}
-				c.Slash = tokFile.LineStart(line) + token.Pos(meta.columns[j]-1)
+			for k := 0; k < lines; k++ {
+				used = append(used, slotPos(next+k, 0))
 			}
+			next += lines
 		}
-		for _, g := range trailingGroups[s] {
-			meta := commentOffsets[g]
-			startLine := importLine
-			for j, c := range g.List {
-				line := startLine + meta.offsets[j]
-				if line < 1 {
-					line = 1
-				}
-				c.Slash = tokFile.LineStart(line) + token.Pos(meta.columns[j]-1)
+
+		pos := slotPos(next, 0)
+		used = append(used, pos)
+		if s.Name != nil {
+			s.Name.NamePos = pos
+		}
+		s.Path.ValuePos = pos
+		s.EndPos = slotPos(next, 1)
+		for k, g := range trailingGroups[s] {
+			for _, c := range g.List {
+				c.Slash = slotPos(next, 2+k)
 			}
 		}
+		next++
 	}
 
 	sort.Sort(byCommentPos(comments))
 
-
-	if len(comments) == 0 {
-		firstSpecLine := tokFile.Line(specs[0].Pos())
-		for _, s := range specs[1:] {
-			p := s.Pos()
-			line := tokFile.Line(p)
-			for previousLine := line - 1; previousLine >= firstSpecLine; {
-				if previousLine > 0 && previousLine < tokFile.LineCount() {
-					tokFile.MergeLine(previousLine)
-					previousLine--
-				} else {
-					req := "Please report what the imports section of your go file looked like."
-					log.Printf("panic avoided: first:%d line:%d previous:%d max:%d. %s",
-						firstSpecLine, line, previousLine, tokFile.LineCount(), req)
-				}
-			}
+	// drop the lines between the slots in use, from the last to the first so the positions keep their lines
+	for i := len(used) - 1; i > 0; i-- {
+		prevLine := tokFile.Line(used[i-1])
+		for line := tokFile.Line(used[i]) - 1; line > prevLine; line-- {
+			tokFile.MergeLine(line)
 		}
 	}
 	return specs
 }
 
 type byImportSpec struct {
-	cfg   *config.Config
-	specs []ast.Spec
+	cfg     *config.Config
+	modules []string
+	specs   []ast.Spec
 }
 
 func (x byImportSpec) Len() int      { return len(x.specs) }
@@ -393,14 +334,21 @@ func (x byImportSpec) Less(i, j int) bool {
 		return igroup < jgroup
 	}
 
-	if ipath != jpath {
-		return ipath < jpath
+	// sections grouped by module order the imports by their module root first
+	if iroot, jroot := importRoot(x.cfg, x.modules, igroup, ipath), importRoot(x.cfg, x.modules, jgroup, jpath); iroot != jroot {
+		return iroot < jroot
 	}
+
 	iname := importName(x.specs[i].(*ast.ImportSpec))
 	jname := importName(x.specs[j].(*ast.ImportSpec))
 
-	if iname != jname {
-		return iname < jname
+	// the imports of a section are ordered by its sort mode, which falls back to path and name
+	var mode gciParse.SortMode
+	if x.cfg != nil && igroup < len(x.cfg.Sections) {
+		mode = x.cfg.SortMode(x.cfg.Sections[igroup])
+	}
+	if c := mode.Compare(&gciParse.GciImports{Path: ipath, Name: iname}, &gciParse.GciImports{Path: jpath, Name: jname}); c != 0 {
+		return c < 0
 	}
 	return importComment(x.specs[i].(*ast.ImportSpec)) < importComment(x.specs[j].(*ast.ImportSpec))
 }
@@ -434,6 +382,15 @@ func importGroup(cfg *config.Config, importPath string, spec *ast.ImportSpec) in
 	return len(cfg.Sections)
 }
 
+// importRoot returns the module root of the import in the section at index group, see section.ModuleRoot, or an empty
+// string if the section does not group its imports by module.
+func importRoot(cfg *config.Config, modules []string, group int, importPath string) string {
+	if cfg == nil || group >= len(cfg.Sections) || !cfg.SubgroupsByModule(cfg.Sections[group]) {
+		return ""
+	}
+	return section.ModuleRoot(modules, importPath)
+}
+
 func defaultImportGroup(importPath string) int {
 	if strings.HasPrefix(importPath, "appengine") {
 		return 2
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Cache remembers file contents that are already formatted, so they do not need to be parsed again.
+// Every entry is an empty file named after the hash of the key of the cache and the content.
+// Entries are created exclusively, so any number of processes can share the same directory.
+type Cache struct {
+	dir string
+	key []byte
+}
+
+// DefaultDir returns the directory of the cache below the user cache directory.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gci"), nil
+}
+
+// Open returns the cache stored in dir. The key must change whenever the formatting result could,
+// e.g. for a different configuration or version.
+func Open(dir string, key []byte) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir, key: key}, nil
+}
+
+func (c *Cache) path(parts [][]byte) string {
+	h := sha256.New()
+	h.Write(c.key)
+	for _, part := range parts {
+		// hash the hash of every part, so the boundaries between the parts can not shift
+		partHash := sha256.Sum256(part)
+		h.Write(partHash[:])
+	}
+	name := hex.EncodeToString(h.Sum(nil))
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+// Has reports whether the content made of parts was added before.
+func (c *Cache) Has(parts ...[]byte) bool {
+	_, err := os.Stat(c.path(parts))
+	return err == nil
+}
+
+// Add records the content made of parts.
+func (c *Cache) Add(parts ...[]byte) error {
+	path := c.path(parts)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		// another run added the same content
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Clean removes the cache directory with all entries.
+func Clean(dir string) error {
+	return os.RemoveAll(dir)
+}
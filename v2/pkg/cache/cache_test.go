@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "gci")
+	c, err := Open(dir, []byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte("package main\n")
+	if c.Has(content) {
+		t.Fatal("empty cache has content")
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Add(content); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if !c.Has(content) {
+		t.Error("added content is missing")
+	}
+
+	other, err := Open(dir, []byte("other key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Has(content) {
+		t.Error("content is shared between keys")
+	}
+
+	if err := Clean(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("cache directory still exists: %v", err)
+	}
+}
@@ -1,21 +1,28 @@
 package config
 
 import (
+	"fmt"
+	"go/token"
+	"slices"
 	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/daixiang0/gci/v2/pkg/cache"
+	"github.com/daixiang0/gci/v2/pkg/parse"
 	"github.com/daixiang0/gci/v2/pkg/section"
 )
 
 var defaultOrder = map[string]int{
-	section.StandardType:    0,
-	section.DefaultType:     1,
-	section.CustomType:      2,
-	section.BlankType:       3,
-	section.DotType:         4,
-	section.AliasType:       5,
-	section.LocalModuleType: 6,
+	section.CgoType:         0,
+	section.StandardType:    1,
+	section.DefaultType:     2,
+	section.CustomType:      3,
+	section.BlankType:       4,
+	section.DotType:         5,
+	section.AliasType:       6,
+	section.LocalModuleType: 7,
 }
 
 type BoolConfig struct {
@@ -26,18 +33,90 @@ type BoolConfig struct {
 	SkipVendor       bool `yaml:"skipVendor"`
 	CustomOrder      bool `yaml:"customOrder"`
 	NoLexOrder       bool `yaml:"noLexOrder"`
+	FormatEmbedded   bool `yaml:"formatEmbedded"`
+	// NoMerge keeps separate import declarations instead of merging them into the first one
+	NoMerge bool `yaml:"noMerge"`
+	// Dedupe drops imports listed twice and aliases which only repeat the package name
+	Dedupe bool `yaml:"dedupe"`
+}
+
+type LineEndings string
+
+const (
+	LineEndingsPreserve LineEndings = "preserve"
+	LineEndingsLF       LineEndings = "lf"
+	LineEndingsCRLF     LineEndings = "crlf"
+)
+
+func ParseLineEndings(in string) (LineEndings, error) {
+	switch l := LineEndings(in); l {
+	case "":
+		return LineEndingsPreserve, nil
+	case LineEndingsPreserve, LineEndingsLF, LineEndingsCRLF:
+		return l, nil
+	default:
+		return "", fmt.Errorf("invalid line endings %q, must be one of %s, %s or %s", in, LineEndingsPreserve, LineEndingsLF, LineEndingsCRLF)
+	}
+}
+
+// GeneratedFiles defines how SkipGenerated detects generated files.
+type GeneratedFiles string
+
+const (
+	// GeneratedFilesStrict only skips files with a // Code generated ... DO NOT EDIT. comment before the package clause
+	GeneratedFilesStrict GeneratedFiles = "strict"
+	// GeneratedFilesLax skips files that mention a marker like "do not edit" anywhere, the default
+	GeneratedFilesLax GeneratedFiles = "lax"
+)
+
+func ParseGeneratedFiles(in string) (GeneratedFiles, error) {
+	switch g := GeneratedFiles(in); g {
+	case "":
+		return GeneratedFilesLax, nil
+	case GeneratedFilesStrict, GeneratedFilesLax:
+		return g, nil
+	default:
+		return "", fmt.Errorf("invalid generated files detection %q, must be %s or %s", in, GeneratedFilesStrict, GeneratedFilesLax)
+	}
 }
 
 type Config struct {
 	BoolConfig
 	Sections          section.SectionList
 	SectionSeparators section.SectionList
+	LineEndings       LineEndings
+	// GeneratedFiles defines which files SkipGenerated skips
+	GeneratedFiles GeneratedFiles
+	// Jobs limits how many files are processed at the same time, values below 1 use GOMAXPROCS
+	Jobs int
+	// KeepGoing processes all files even if some of them fail and reports every failure at the end
+	KeepGoing bool
+	// Cache skips files which were already formatted in a previous run, it is disabled if nil
+	Cache *cache.Cache
+	// NoStdin does not read Go source from STDIN, e.g. because STDIN lists the files to process
+	NoStdin bool
+	// StdinFilename is the path of the file whose content is read from STDIN
+	StdinFilename string
+	// Aliases maps import paths to the only alias they may be imported with
+	Aliases map[string]string
+	// Sort orders the imports within the sections, unless SectionSort sets another mode for the section
+	Sort parse.SortMode
+	// SectionSort maps the names of sections to the order of their imports, like prefix(github.com/org) to natural
+	SectionSort map[string]parse.SortMode
+	// SubgroupByModule lists the names of the sections whose imports are grouped by the module they belong to
+	SubgroupByModule []string
 }
 
 type YamlConfig struct {
-	Cfg                     BoolConfig `yaml:",inline"`
-	SectionStrings          []string   `yaml:"sections"`
-	SectionSeparatorStrings []string   `yaml:"sectionseparators"`
+	Cfg                     BoolConfig        `yaml:",inline"`
+	SectionStrings          []string          `yaml:"sections"`
+	SectionSeparatorStrings []string          `yaml:"sectionseparators"`
+	LineEndings             string            `yaml:"lineEndings"`
+	GeneratedFiles          string            `yaml:"generatedFiles"`
+	Aliases                 map[string]string `yaml:"aliases"`
+	Sort                    string            `yaml:"sort"`
+	SectionSort             map[string]string `yaml:"sectionSort"`
+	SubgroupByModule        []string          `yaml:"subgroupByModule"`
 
 	ModPath string `yaml:"-"`
 }
@@ -76,7 +155,139 @@ func (g YamlConfig) Parse() (*Config, error) {
 		sectionSeparators = section.DefaultSectionSeparators()
 	}
 
-	return &Config{g.Cfg, sections, sectionSeparators}, nil
+	lineEndings, err := ParseLineEndings(g.LineEndings)
+	if err != nil {
+		return nil, err
+	}
+
+	generatedFiles, err := ParseGeneratedFiles(g.GeneratedFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkAliases(g.Aliases); err != nil {
+		return nil, err
+	}
+
+	sortMode, err := parse.ParseSortMode(g.Sort)
+	if err != nil {
+		return nil, err
+	}
+	sectionSort, err := ParseSectionSort(sections, g.SectionSort)
+	if err != nil {
+		return nil, err
+	}
+	subgroupByModule, err := ParseSubgroupByModule(sections, g.SubgroupByModule)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		BoolConfig:        g.Cfg,
+		Sections:          sections,
+		SectionSeparators: sectionSeparators,
+		LineEndings:       lineEndings,
+		GeneratedFiles:    generatedFiles,
+		Aliases:           g.Aliases,
+		Sort:              sortMode,
+		SectionSort:       sectionSort,
+		SubgroupByModule:  subgroupByModule,
+	}, nil
+}
+
+// ParseAliases parses path=alias pairs, like k8s.io/api/core/v1=corev1.
+func ParseAliases(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	aliases := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		importPath, alias, found := strings.Cut(pair, "=")
+		if !found || importPath == "" {
+			return nil, fmt.Errorf("invalid alias %q, must be path=alias", pair)
+		}
+		aliases[importPath] = alias
+	}
+	return aliases, checkAliases(aliases)
+}
+
+func checkAliases(aliases map[string]string) error {
+	for importPath, alias := range aliases {
+		if !token.IsIdentifier(alias) || alias == "_" {
+			return fmt.Errorf("invalid alias %q of %s, must be an identifier", alias, importPath)
+		}
+	}
+	return nil
+}
+
+// ParseSectionSort parses the sort modes of sections, which must be part of sections. The names of the sections are
+// normalized, so Prefix(github.com/org) sets the mode of prefix(github.com/org).
+func ParseSectionSort(sections section.SectionList, modes map[string]string) (map[string]parse.SortMode, error) {
+	if len(modes) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]parse.SortMode, len(modes))
+	for name, mode := range modes {
+		s, err := sectionName(sections, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sort mode of section %s: %w", name, err)
+		}
+		if out[s], err = parse.ParseSortMode(mode); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// ParseSubgroupByModule parses the names of the sections whose imports are grouped by module, which must be part of
+// sections. It returns the normalized names in order.
+func ParseSubgroupByModule(sections section.SectionList, names []string) ([]string, error) {
+	var out []string
+	for _, name := range names {
+		s, err := sectionName(sections, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid section %s to group by module: %w", name, err)
+		}
+		out = append(out, s)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+// sectionName returns the normalized name of the section, which must be one of sections.
+func sectionName(sections section.SectionList, name string) (string, error) {
+	parsed, err := section.Parse([]string{name})
+	if err != nil {
+		return "", err
+	}
+	if len(parsed) == 0 || !slices.Contains(sections.String(), parsed[0].String()) {
+		return "", fmt.Errorf("it is not one of the sections %s", strings.Join(sections.String(), ","))
+	}
+	return parsed[0].String(), nil
+}
+
+// SortMode returns the order of the imports within section s.
+func (c Config) SortMode(s section.Section) parse.SortMode {
+	if mode, ok := c.SectionSort[s.String()]; ok {
+		return mode
+	}
+	return c.Sort
+}
+
+// SubgroupsByModule reports whether the imports of section s are separated by blank lines into groups of the same
+// module.
+func (c Config) SubgroupsByModule(s section.Section) bool {
+	return slices.Contains(c.SubgroupByModule, s.String())
+}
+
+// SortsLexically reports whether the imports of every section are sorted by path, which gofmt keeps.
+func (c Config) SortsLexically() bool {
+	for _, s := range c.Sections {
+		if mode := c.SortMode(s); mode != "" && mode != parse.SortLexical {
+			return false
+		}
+	}
+	return true
 }
 
 func ParseConfig(in string) (*Config, error) {
@@ -4,6 +4,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/daixiang0/gci/v2/pkg/parse"
 	"github.com/daixiang0/gci/v2/pkg/section"
 )
 
@@ -66,3 +67,58 @@ func TestParseNoLexOrder(t *testing.T) {
 		t.Fatalf("unexpected sections: got=%v want=%v", gciCfg.Sections, want)
 	}
 }
+
+func TestParseAliases(t *testing.T) {
+	aliases, err := ParseAliases([]string{"k8s.io/api/core/v1=corev1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"k8s.io/api/core/v1": "corev1"}; !reflect.DeepEqual(want, aliases) {
+		t.Fatalf("unexpected aliases: got=%v want=%v", aliases, want)
+	}
+
+	for _, pair := range []string{"k8s.io/api/core/v1", "=corev1", "k8s.io/api/core/v1=core-v1", "k8s.io/api/core/v1=_"} {
+		if _, err := ParseAliases([]string{pair}); err == nil {
+			t.Errorf("expected an error for %q", pair)
+		}
+	}
+}
+
+func TestParseSectionSort(t *testing.T) {
+	sections := section.SectionList{section.Standard{}, section.Default{}, section.Custom{Prefix: "github.com/daixiang0"}}
+	modes, err := ParseSectionSort(sections, map[string]string{"Prefix(github.com/daixiang0)": "natural"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Sections: sections, Sort: parse.SortByAlias, SectionSort: modes}
+	if mode := cfg.SortMode(section.Custom{Prefix: "github.com/daixiang0"}); mode != parse.SortNatural {
+		t.Errorf("unexpected sort mode of the custom section: got=%s want=%s", mode, parse.SortNatural)
+	}
+	if mode := cfg.SortMode(section.Default{}); mode != parse.SortByAlias {
+		t.Errorf("unexpected sort mode of the default section: got=%s want=%s", mode, parse.SortByAlias)
+	}
+
+	for _, modes := range []map[string]string{{"prefix(example.com)": "natural"}, {"default": "numeric"}} {
+		if _, err := ParseSectionSort(sections, modes); err == nil {
+			t.Errorf("expected an error for %v", modes)
+		}
+	}
+}
+
+func TestParseSubgroupByModule(t *testing.T) {
+	sections := section.SectionList{section.Standard{}, section.Default{}}
+	names, err := ParseSubgroupByModule(sections, []string{"Default", "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"default"}; !reflect.DeepEqual(want, names) {
+		t.Fatalf("unexpected sections: got=%v want=%v", names, want)
+	}
+	if cfg := (Config{SubgroupByModule: names}); !cfg.SubgroupsByModule(section.Default{}) || cfg.SubgroupsByModule(section.Standard{}) {
+		t.Errorf("expected only the default section to be grouped by module")
+	}
+
+	if _, err := ParseSubgroupByModule(sections, []string{"blank"}); err == nil {
+		t.Error("expected an error for a section that is not configured")
+	}
+}
@@ -0,0 +1,121 @@
+package gci
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/daixiang0/gci/v2/pkg/config"
+)
+
+const benchmarkSource = `package main
+
+import (
+	"github.com/daixiang0/gci/v2"
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Println(os.Args, gci.Version)
+}
+`
+
+// syntheticTree creates dirs directories with files Go files each.
+func syntheticTree(b *testing.B, dirs, files int) string {
+	b.Helper()
+	root := b.TempDir()
+	for d := 0; d < dirs; d++ {
+		dir := filepath.Join(root, fmt.Sprintf("pkg%d", d))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			b.Fatal(err)
+		}
+		for f := 0; f < files; f++ {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.go", f)), []byte(benchmarkSource), 0o644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	return root
+}
+
+// processFilesUnbounded is how files were processed before the worker pool:
+// every path is collected first and each file gets its own goroutine.
+func processFilesUnbounded(paths []string, cfg config.Config, fileFunc fileFormattingFunc) error {
+	var files []FileObj
+	var searchErr error
+	GoFilesInPathsGenerator(paths, false)(func(file FileObj, err error) bool {
+		if err != nil {
+			searchErr = err
+			return false
+		}
+		files = append(files, file)
+		return true
+	})
+	if searchErr != nil {
+		return searchErr
+	}
+	var taskGroup errgroup.Group
+	for _, file := range files {
+		file := file
+		taskGroup.Go(func() error {
+			unmodifiedFile, formattedFile, err := LoadFormatGoFile(file, cfg)
+			if err != nil {
+				return err
+			}
+			return fileFunc(file.Path, unmodifiedFile, formattedFile)
+		})
+	}
+	return taskGroup.Wait()
+}
+
+func BenchmarkProcessFiles(b *testing.B) {
+	root := syntheticTree(b, 50, 100)
+	cfg, err := config.ParseConfig("")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// the number of goroutines shows how many files are held in memory at the same time
+	var peakGoroutines atomic.Int64
+	discard := func(string, []byte, []byte) error {
+		n := int64(runtime.NumGoroutine())
+		for {
+			peak := peakGoroutines.Load()
+			if n <= peak || peakGoroutines.CompareAndSwap(peak, n) {
+				return nil
+			}
+		}
+	}
+	report := func(b *testing.B) {
+		b.ReportMetric(float64(peakGoroutines.Swap(0)), "peak-goroutines")
+	}
+
+	b.Run("unbounded", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := processFilesUnbounded([]string{root}, *cfg, discard); err != nil {
+				b.Fatal(err)
+			}
+		}
+		report(b)
+	})
+	for _, jobs := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			cfg := *cfg
+			cfg.Jobs = jobs
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := ProcessFiles(GoFilesInPathsGenerator([]string{root}, false), cfg, discard); err != nil {
+					b.Fatal(err)
+				}
+			}
+			report(b)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package gci
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/daixiang0/gci/v2/pkg/config"
+	"github.com/daixiang0/gci/v2/pkg/section"
+)
+
+// CacheKey identifies the formatting results of cfg with the given gci version.
+// Options that do not change the formatted files, like the number of jobs, are left out.
+func CacheKey(cfg config.Config, version string) []byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "version=%s\n", version)
+
+	boolCfg := cfg.BoolConfig
+	boolCfg.Debug = false
+	fmt.Fprintf(h, "bool=%+v\n", boolCfg)
+	for _, s := range cfg.Sections {
+		fmt.Fprintf(h, "section=%T%+v\n", s, s)
+	}
+	for _, s := range cfg.SectionSeparators {
+		fmt.Fprintf(h, "separator=%T%+v\n", s, s)
+	}
+	fmt.Fprintf(h, "lineEndings=%s\n", cfg.LineEndings)
+	fmt.Fprintf(h, "generatedFiles=%s\n", cfg.GeneratedFiles)
+	fmt.Fprintf(h, "subgroupByModule=%s\n", strings.Join(cfg.SubgroupByModule, ","))
+	fmt.Fprintf(h, "sort=%s\n", cfg.Sort)
+	sortedSections := make([]string, 0, len(cfg.SectionSort))
+	for name := range cfg.SectionSort {
+		sortedSections = append(sortedSections, name)
+	}
+	sort.Strings(sortedSections)
+	for _, name := range sortedSections {
+		fmt.Fprintf(h, "sectionSort=%s %s\n", name, cfg.SectionSort[name])
+	}
+	importPaths := make([]string, 0, len(cfg.Aliases))
+	for importPath := range cfg.Aliases {
+		importPaths = append(importPaths, importPath)
+	}
+	sort.Strings(importPaths)
+	for _, importPath := range importPaths {
+		fmt.Fprintf(h, "alias=%s %s\n", importPath, cfg.Aliases[importPath])
+	}
+	return h.Sum(nil)
+}
+
+// cacheEntry returns the parts identifying the formatting result of src.
+// A localmodule section and the grouping by module make the result depend on the module of the file as well.
+func cacheEntry(path string, kind, src []byte, cfg config.Config) ([][]byte, error) {
+	sections, err := cfg.Sections.ForFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entry := [][]byte{kind, src}
+	for _, s := range sections {
+		if m, ok := s.(*section.LocalModule); ok {
+			entry = append(entry, []byte(strings.Join(append([]string{m.Path}, m.Workspace...), "\n")))
+		}
+	}
+	if len(cfg.SubgroupByModule) > 0 {
+		// the imports are grouped by the modules the go.mod requires
+		modules, err := section.RequiredModules(path)
+		if err != nil {
+			return nil, err
+		}
+		entry = append(entry, []byte(strings.Join(modules, "\n")))
+	}
+	return entry, nil
+}
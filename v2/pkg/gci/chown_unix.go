@@ -0,0 +1,23 @@
+//go:build unix
+
+package gci
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// chown gives the file the owner and group of the original file.
+// Only privileged users may hand files to someone else, so permission errors are ignored.
+func chown(f *os.File, original os.FileInfo) error {
+	stat, ok := original.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	err := f.Chown(int(stat.Uid), int(stat.Gid))
+	if errors.Is(err, os.ErrPermission) {
+		return nil
+	}
+	return err
+}
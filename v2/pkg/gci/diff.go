@@ -2,23 +2,105 @@ package gci
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"runtime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+
+	"github.com/daixiang0/gci/v2/pkg/config"
+	"github.com/daixiang0/gci/v2/pkg/parse"
+	"github.com/daixiang0/gci/v2/pkg/section"
+	"github.com/daixiang0/gci/v2/pkg/specificity"
+)
+
+const DefaultDiffContext = 3
+
+// diffTimeLayout is the format of the timestamps in the file headers of `diff -u`.
+const diffTimeLayout = "2006-01-02 15:04:05.000000000 -0700"
+
+// diffTime returns the timestamp of the file headers, tests replace it to get stable output.
+var diffTime = time.Now
+
+type DiffOptions struct {
+	// Context is the number of unchanged lines shown around every change.
+	Context int
+	Color   bool
+	// SideBySide shows the original and the formatted import block next to each other instead of a unified diff.
+	SideBySide bool
+}
+
+type ColorMode string
+
+const (
+	ColorAuto   ColorMode = "auto"
+	ColorAlways ColorMode = "always"
+	ColorNever  ColorMode = "never"
+)
+
+func ParseColorMode(in string) (ColorMode, error) {
+	switch mode := ColorMode(strings.ToLower(in)); mode {
+	case ColorAuto, ColorAlways, ColorNever:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid color mode %q, must be one of %s, %s or %s", in, ColorAuto, ColorAlways, ColorNever)
+	}
+}
+
+// Enabled reports whether the output written to out should be colored.
+// In auto mode colors are only used for terminals and are disabled by a non-empty NO_COLOR, see https://no-color.org.
+func (m ColorMode) Enabled(out *os.File) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	stat, err := out.Stat()
+	if err != nil {
+		return false
+	}
+	return (stat.Mode() & os.ModeCharDevice) != 0
+}
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiCyan  = "\x1b[36m"
 )
 
-func diffToString(filename string, b1, b2 []byte) (string, error) {
-	data, err := diffBytes(b1, b2, filename)
+func colorize(enabled bool, code, s string) string {
+	if !enabled || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func DefaultDiffOptions() DiffOptions {
+	return DiffOptions{Context: DefaultDiffContext}
+}
+
+func diffToString(filename string, b1, b2 []byte, cfg config.Config, opts DiffOptions) (string, error) {
+	data, err := renderDiff(b1, b2, filename, cfg, opts)
 	if err != nil {
 		return "", err
 	}
 	return string(data), nil
 }
 
-func diffFormattedFiles(filename string, b1, b2 []byte) error {
-	data, err := diffBytes(b1, b2, filename)
+func diffFormattedFiles(filename string, b1, b2 []byte, cfg config.Config, opts DiffOptions) error {
+	data, err := renderDiff(b1, b2, filename, cfg, opts)
 	if err != nil {
 		return err
 	}
@@ -26,63 +108,317 @@ func diffFormattedFiles(filename string, b1, b2 []byte) error {
 	return err
 }
 
-func diffBytes(b1, b2 []byte, filename string) ([]byte, error) {
-	f1, err := writeTempFile("", "gci", b1)
-	if err != nil {
-		return nil, err
+func renderDiff(b1, b2 []byte, filename string, cfg config.Config, opts DiffOptions) ([]byte, error) {
+	var data []byte
+	var err error
+	if opts.SideBySide {
+		data, err = sideBySide(b1, b2, filename, cfg, opts)
+	} else {
+		data, err = diffBytes(b1, b2, filename, opts)
+	}
+	if err != nil || len(data) == 0 {
+		return data, err
 	}
-	defer os.Remove(f1)
 
-	f2, err := writeTempFile("", "gci", b2)
+	// lines in front of the diff are ignored by patch, so they can tell which imports were renamed or removed
+	var notes bytes.Buffer
+	for _, change := range importChanges(b1, filename, cfg) {
+		notes.WriteString(colorize(opts.Color, ansiCyan, filepath.ToSlash(filename)+": "+change))
+		notes.WriteByte('\n')
+	}
+	return append(notes.Bytes(), data...), nil
+}
+
+// importChanges lists the imports that are renamed to their canonical alias, the ones that keep their name
+// because of a conflict, and the duplicate imports and redundant aliases the dedupe option removes from src.
+func importChanges(src []byte, filename string, cfg config.Config) []string {
+	src, _ = normalize(src)
+	src, changes, conflicts, err := parse.RewriteAliases(src, filename, cfg.Aliases)
 	if err != nil {
-		return nil, err
+		return nil
 	}
-	defer os.Remove(f2)
+	changes = append(changes, conflicts...)
+	if !cfg.Dedupe {
+		return changes
+	}
+	if !cfg.NoMerge {
+		if src, err = parse.MergeImportDecls(src, filename); err != nil {
+			return changes
+		}
+	}
+	_, removed, err := parse.DedupeImports(src, filename)
+	if err != nil {
+		return changes
+	}
+	return append(changes, removed...)
+}
 
-	cmd := "diff"
-	if runtime.GOOS == "plan9" {
-		cmd = "/bin/ape/diff"
+func diffBytes(b1, b2 []byte, filename string, opts DiffOptions) ([]byte, error) {
+	if opts.Context < 0 {
+		return nil, fmt.Errorf("invalid diff context %d: must not be negative", opts.Context)
+	}
+	if bytes.Equal(b1, b2) {
+		return nil, nil
 	}
 
-	data, err := exec.Command(cmd, "-u", f1, f2).CombinedOutput()
-	if len(data) > 0 {
-		return replaceTempFilename(data, filename)
+	f := filepath.ToSlash(filename)
+	// like diff -u, the file names are followed by a tab and the modification time, which is now for both
+	stamp := "\t" + diffTime().Format(diffTimeLayout)
+	before, after := string(b1), string(b2)
+	edits := myers.ComputeEdits(span.URIFromPath(filename), before, after)
+	unified := toUnified(f+".orig"+stamp, f+stamp, before, edits, opts.Context)
+
+	var buf bytes.Buffer
+	writeUnified(&buf, unified, opts.Color)
+	return buf.Bytes(), nil
+}
+
+// toUnified mirrors gotextdiff.ToUnified, but takes the number of context
+// lines instead of using a fixed value, and counts the lines of the new text
+// like diff -u, which gotextdiff gets wrong from the second hunk on. The edits
+// must be line based, which is always the case for the edits computed by
+// myers.ComputeEdits.
+func toUnified(from, to, content string, edits []gotextdiff.TextEdit, context int) gotextdiff.Unified {
+	u := gotextdiff.Unified{
+		From: from,
+		To:   to,
+	}
+	if len(edits) == 0 {
+		return u
 	}
-	return data, err
+
+	lines := splitLines(content)
+	var h *gotextdiff.Hunk
+	last := 0
+	// the number of lines the edits so far inserted minus the ones they deleted, which moves the lines of the new text
+	shift := 0
+	for _, edit := range edits {
+		start := edit.Span.Start().Line() - 1
+		end := edit.Span.End().Line() - 1
+		switch {
+		case h != nil && start == last:
+			// direct extension of the previous edit
+		case h != nil && start <= last+2*context:
+			// close enough to share the context lines with the previous edit
+			addEqualLines(h, lines, last, start)
+		default:
+			if h != nil {
+				addEqualLines(h, lines, last, last+context)
+				u.Hunks = append(u.Hunks, h)
+			}
+			h = &gotextdiff.Hunk{
+				FromLine: start + 1,
+				ToLine:   start + shift + 1,
+			}
+			delta := addEqualLines(h, lines, start-context, start)
+			h.FromLine -= delta
+			h.ToLine -= delta
+		}
+		last = start
+		for i := start; i < end; i++ {
+			h.Lines = append(h.Lines, gotextdiff.Line{Kind: gotextdiff.Delete, Content: lines[i]})
+			last++
+			shift--
+		}
+		if edit.NewText != "" {
+			for _, line := range splitLines(edit.NewText) {
+				h.Lines = append(h.Lines, gotextdiff.Line{Kind: gotextdiff.Insert, Content: line})
+				shift++
+			}
+		}
+	}
+	if h != nil {
+		addEqualLines(h, lines, last, last+context)
+		u.Hunks = append(u.Hunks, h)
+	}
+	return u
 }
 
-func writeTempFile(dir, prefix string, data []byte) (string, error) {
-	file, err := os.CreateTemp(dir, prefix)
-	if err != nil {
-		return "", err
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func addEqualLines(h *gotextdiff.Hunk, lines []string, start, end int) int {
+	delta := 0
+	for i := start; i < end; i++ {
+		if i < 0 {
+			continue
+		}
+		if i >= len(lines) {
+			return delta
+		}
+		h.Lines = append(h.Lines, gotextdiff.Line{Kind: gotextdiff.Equal, Content: lines[i]})
+		delta++
+	}
+	return delta
+}
+
+// writeUnified prints the diff in the same form as `diff -u`, including the
+// range notation for empty ranges, so existing consumers keep working.
+func writeUnified(buf *bytes.Buffer, u gotextdiff.Unified, color bool) {
+	if len(u.Hunks) == 0 {
+		return
 	}
-	_, err = file.Write(data)
-	if err1 := file.Close(); err == nil {
-		err = err1
+	fmt.Fprintf(buf, "%s\n", colorize(color, ansiBold, "--- "+u.From))
+	fmt.Fprintf(buf, "%s\n", colorize(color, ansiBold, "+++ "+u.To))
+	for _, hunk := range u.Hunks {
+		fromCount, toCount := 0, 0
+		for _, l := range hunk.Lines {
+			switch l.Kind {
+			case gotextdiff.Delete:
+				fromCount++
+			case gotextdiff.Insert:
+				toCount++
+			default:
+				fromCount++
+				toCount++
+			}
+		}
+		header := fmt.Sprintf("@@ -%s +%s @@", hunkRange(hunk.FromLine, fromCount), hunkRange(hunk.ToLine, toCount))
+		fmt.Fprintf(buf, "%s\n", colorize(color, ansiCyan, header))
+		for _, l := range hunk.Lines {
+			content := strings.TrimSuffix(l.Content, "\n")
+			switch l.Kind {
+			case gotextdiff.Delete:
+				buf.WriteString(colorize(color, ansiRed, "-"+content))
+			case gotextdiff.Insert:
+				buf.WriteString(colorize(color, ansiGreen, "+"+content))
+			default:
+				buf.WriteString(" " + content)
+			}
+			if strings.HasSuffix(l.Content, "\n") {
+				buf.WriteByte('\n')
+			} else {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		// an empty range refers to the line just before it
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	default:
+		return fmt.Sprintf("%d,%d", start, count)
+	}
+}
+
+const sideBySideTabWidth = 4
+
+type importBlockLine struct {
+	text, label string
+}
+
+func matchSection(cfg config.Config, imp *parse.GciImports) section.Section {
+	var best section.Section
+	var bestSpec specificity.MatchSpecificity = specificity.MisMatch{}
+	for _, sec := range cfg.Sections {
+		if spec := sec.MatchSpecificity(imp); spec.IsMoreSpecific(bestSpec) {
+			best, bestSpec = sec, spec
+		}
+	}
+	return best
+}
+
+// importBlock returns the lines of the import declarations of the file.
+// Every line that holds an import path is labeled with the section the import belongs to. It is empty if the file
+// has no imports.
+func importBlock(src []byte, filename string, cfg config.Config) ([]importBlockLine, error) {
+	imports, headEnd, tailStart, _, _, err := parse.ParseFile(src, filename)
+	if errors.Is(err, parse.NoImportError{}) {
+		return nil, nil
 	}
 	if err != nil {
-		os.Remove(file.Name())
-		return "", err
+		return nil, err
+	}
+	cfg.Sections, err = cfg.Sections.ForFile(filename)
+	if err != nil {
+		return nil, err
 	}
-	return file.Name(), nil
+
+	// tailStart and the end of an import include the following linebreak
+	block := strings.Split(strings.TrimSuffix(string(src[headEnd:tailStart]), "\n"), "\n")
+	lines := make([]importBlockLine, len(block))
+	for i, text := range block {
+		lines[i].text = strings.ReplaceAll(strings.TrimRight(text, "\r"), "\t", strings.Repeat(" ", sideBySideTabWidth))
+	}
+	for _, imp := range imports {
+		if s := matchSection(cfg, imp); s != nil {
+			lines[strings.Count(string(src[headEnd:imp.End-1]), "\n")].label = s.String()
+		}
+	}
+	return lines, nil
 }
 
-func replaceTempFilename(diff []byte, filename string) ([]byte, error) {
-	bs := bytes.SplitN(diff, []byte{'\n'}, 3)
-	if len(bs) < 3 {
-		return nil, fmt.Errorf("got unexpected diff for %s", filename)
+func sideBySide(b1, b2 []byte, filename string, cfg config.Config, opts DiffOptions) ([]byte, error) {
+	if bytes.Equal(b1, b2) {
+		return nil, nil
 	}
 
-	var t0, t1 []byte
-	if i := bytes.LastIndexByte(bs[0], '\t'); i != -1 {
-		t0 = bs[0][i:]
+	left, err := importBlock(b1, filename, cfg)
+	if err != nil {
+		return nil, err
 	}
-	if i := bytes.LastIndexByte(bs[1], '\t'); i != -1 {
-		t1 = bs[1][i:]
+	right, err := importBlock(b2, filename, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil && right == nil {
+		// the change is outside of the imports, e.g. of the line endings
+		return diffBytes(b1, b2, filename, opts)
 	}
+	color := opts.Color
 
-	f := filepath.ToSlash(filename)
-	bs[0] = []byte(fmt.Sprintf("--- %s%s", f+".orig", t0))
-	bs[1] = []byte(fmt.Sprintf("+++ %s%s", f, t1))
-	return bytes.Join(bs, []byte{'\n'}), nil
+	const leftTitle, rightTitle = "original", "formatted"
+	leftWidth, rightWidth := utf8.RuneCountInString(leftTitle), utf8.RuneCountInString(rightTitle)
+	for _, l := range left {
+		leftWidth = max(leftWidth, utf8.RuneCountInString(l.text))
+	}
+	for _, l := range right {
+		rightWidth = max(rightWidth, utf8.RuneCountInString(l.text))
+	}
+	cell := func(text string, width int, code string) string {
+		return colorize(color && code != "", code, text) + strings.Repeat(" ", width-utf8.RuneCountInString(text))
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n", colorize(color, ansiBold, filepath.ToSlash(filename)+":"))
+	fmt.Fprintf(&buf, "%s   %s\n", cell(leftTitle, leftWidth, ansiBold), colorize(color, ansiBold, rightTitle))
+	for i := 0; i < max(len(left), len(right)); i++ {
+		var l, r importBlockLine
+		marker := " | "
+		switch {
+		case i >= len(right):
+			l, marker = left[i], " < "
+		case i >= len(left):
+			r, marker = right[i], " > "
+		default:
+			l, r = left[i], right[i]
+			if l.text == r.text {
+				marker = "   "
+			}
+		}
+		leftCode, rightCode := ansiRed, ansiGreen
+		if marker == "   " {
+			leftCode, rightCode = "", ""
+		}
+
+		line := cell(l.text, leftWidth, leftCode) + marker
+		if r.label != "" {
+			line += cell(r.text, rightWidth, rightCode) + "  " + colorize(color, ansiCyan, r.label)
+		} else {
+			line += colorize(color && rightCode != "", rightCode, r.text)
+		}
+		buf.WriteString(strings.TrimRight(line, " "))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,243 @@
+package gci
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daixiang0/gci/v2/pkg/config"
+)
+
+// stableDiffTime makes the timestamps in the file headers of diffs predictable.
+func stableDiffTime(t *testing.T) {
+	t.Helper()
+	old := diffTime
+	diffTime = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC) }
+	t.Cleanup(func() { diffTime = old })
+}
+
+const stamp = "\t2024-01-02 03:04:05.000000006 +0000"
+
+func TestDiffBytes(t *testing.T) {
+	stableDiffTime(t)
+	before := "package main\nimport (\n\t\"b\"\n\t\"a\"\n)\nfunc main() {}\n"
+	after := "package main\nimport (\n\t\"a\"\n\t\"b\"\n)\nfunc main() {}\n"
+
+	testCases := []struct {
+		name    string
+		context int
+		before  string
+		after   string
+		want    string
+	}{
+		{
+			name:    "no changes",
+			context: DefaultDiffContext,
+			before:  before,
+			after:   before,
+			want:    "",
+		},
+		{
+			name:    "default context",
+			context: DefaultDiffContext,
+			before:  before,
+			after:   after,
+			want: "--- main.go.orig" + stamp + "\n+++ main.go" + stamp + `
+@@ -1,6 +1,6 @@
+ package main
+ import (
+-	"b"
+ 	"a"
++	"b"
+ )
+ func main() {}
+`,
+		},
+		{
+			name:    "no context",
+			context: 0,
+			before:  before,
+			after:   after,
+			want: "--- main.go.orig" + stamp + "\n+++ main.go" + stamp + `
+@@ -3 +2,0 @@
+-	"b"
+@@ -4,0 +4 @@
++	"b"
+`,
+		},
+		{
+			name:    "missing newline at end of file",
+			context: 1,
+			before:  "a\nb",
+			after:   "a\nc\n",
+			want: "--- main.go.orig" + stamp + "\n+++ main.go" + stamp + `
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := diffBytes([]byte(tc.before), []byte(tc.after), "main.go", DiffOptions{Context: tc.context})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("diff mismatch:\ngot:\n%s\nwant:\n%s", string(got), tc.want)
+			}
+		})
+	}
+}
+
+func TestDiffBytesMatchesDiffU(t *testing.T) {
+	diffPath, err := exec.LookPath("diff")
+	if err != nil {
+		t.Skip("diff is not installed")
+	}
+
+	var before, after strings.Builder
+	for i := 1; i <= 40; i++ {
+		line := fmt.Sprintf("line %d\n", i)
+		before.WriteString(line)
+		switch i {
+		case 3, 4, 30:
+			// deleted
+		case 10:
+			after.WriteString(line + "new a\nnew b\nnew c\n")
+		case 13:
+			after.WriteString("changed 13\n")
+		case 38:
+			after.WriteString(line + "new d\n")
+		default:
+			after.WriteString(line)
+		}
+	}
+	dir := t.TempDir()
+	beforePath, afterPath := filepath.Join(dir, "before"), filepath.Join(dir, "after")
+	if err := os.WriteFile(beforePath, []byte(before.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(afterPath, []byte(after.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the hunks of both, without the file headers
+	hunks := func(diff []byte) string {
+		lines := strings.SplitN(string(diff), "\n", 3)
+		return lines[len(lines)-1]
+	}
+	for _, context := range []int{0, 1, 3, 5} {
+		t.Run(fmt.Sprintf("context %d", context), func(t *testing.T) {
+			// diff exits with 1 if the files differ
+			want, _ := exec.Command(diffPath, fmt.Sprintf("-U%d", context), beforePath, afterPath).Output()
+			got, err := diffBytes([]byte(before.String()), []byte(after.String()), "main.go", DiffOptions{Context: context})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hunks(got) != hunks(want) {
+				t.Errorf("diff mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestDiffBytesNegativeContext(t *testing.T) {
+	if _, err := diffBytes([]byte("a\n"), []byte("b\n"), "main.go", DiffOptions{Context: -1}); err == nil {
+		t.Error("expected an error for a negative context")
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	cfg, err := config.ParseConfig("sections:\n  - Standard\n  - Default\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := "package main\n\nimport (\n\t\"github.com/daixiang0/gci\"\n\t\"fmt\"\n)\n"
+	after := "package main\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/daixiang0/gci\"\n)\n"
+	want := `main.go:
+original                         formatted
+import (                         import (
+    "github.com/daixiang0/gci" |     "fmt"                       standard
+    "fmt"                      |
+)                              |     "github.com/daixiang0/gci"  default
+                               > )
+`
+
+	got, err := renderDiff([]byte(before), []byte(after), "main.go", *cfg, DiffOptions{SideBySide: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("side by side mismatch:\ngot:\n%s\nwant:\n%s", string(got), want)
+	}
+}
+
+func TestSideBySideWithoutImports(t *testing.T) {
+	stableDiffTime(t)
+	cfg, err := config.ParseConfig("sections:\n  - Standard\n  - Default\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the line endings change, there is no import block to show
+	before, after := []byte("package main\r\n"), []byte("package main\n")
+	got, err := renderDiff(before, after, "main.go", *cfg, DiffOptions{Context: DefaultDiffContext, SideBySide: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := diffBytes(before, after, "main.go", DiffOptions{Context: DefaultDiffContext})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) == 0 || string(got) != string(want) {
+		t.Errorf("expected the unified diff:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDiffListsDedupedImports(t *testing.T) {
+	cfg, err := config.ParseConfig("sections:\n  - Standard\n  - Default\ndedupe: true\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := "package main\n\nimport (\n\t\"fmt\"\n\tyaml \"gopkg.in/yaml.v3\"\n\t\"fmt\"\n)\n"
+	_, after, err := LoadFormat([]byte(before), "main.go", *cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := renderDiff([]byte(before), after, "main.go", *cfg, DefaultDiffOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "main.go: removed redundant alias yaml of \"gopkg.in/yaml.v3\"\n" +
+		"main.go: removed duplicate import \"fmt\"\n" +
+		"--- main.go.orig\t"
+	if !strings.HasPrefix(string(got), want) {
+		t.Errorf("diff does not start with the removed imports:\n%s", got)
+	}
+}
+
+func TestColoredDiff(t *testing.T) {
+	stableDiffTime(t)
+	got, err := diffBytes([]byte("a\n"), []byte("b\n"), "main.go", DiffOptions{Context: DefaultDiffContext, Color: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ansiBold + "--- main.go.orig" + stamp + ansiReset + "\n" +
+		ansiBold + "+++ main.go" + stamp + ansiReset + "\n" +
+		ansiCyan + "@@ -1 +1 @@" + ansiReset + "\n" +
+		ansiRed + "-a" + ansiReset + "\n" +
+		ansiGreen + "+b" + ansiReset + "\n"
+	if string(got) != want {
+		t.Errorf("colored diff mismatch:\ngot:\n%q\nwant:\n%q", string(got), want)
+	}
+}
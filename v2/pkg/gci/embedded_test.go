@@ -0,0 +1,178 @@
+package gci
+
+import (
+	"testing"
+
+	"github.com/daixiang0/gci/v2/pkg/config"
+)
+
+func embeddedTestConfig(t *testing.T) config.Config {
+	cfg, err := config.ParseConfig(`sections:
+  - Standard
+  - Default
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *cfg
+}
+
+func TestLoadFormatEmbeddedMarkdown(t *testing.T) {
+	in := "# Usage\n" +
+		"\n" +
+		"```go\n" +
+		"package main\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"github.com/daixiang0/gci\"\n" +
+		"\t\"fmt\"\n" +
+		")\n" +
+		"```\n" +
+		"\n" +
+		"```sh\n" +
+		"import ( b a )\n" +
+		"```\n" +
+		"\n" +
+		"1. In a list:\n" +
+		"   ~~~~golang\n" +
+		"   package main\n" +
+		"\n" +
+		"   import (\n" +
+		"   \t\"os\"\n" +
+		"   \t\"fmt\"\n" +
+		"   )\n" +
+		"   ~~~~\n" +
+		"\n" +
+		"```go\n" +
+		"fmt.Println(\"not a file\")\n" +
+		"```\n"
+	out := "# Usage\n" +
+		"\n" +
+		"```go\n" +
+		"package main\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"fmt\"\n" +
+		"\n" +
+		"\t\"github.com/daixiang0/gci\"\n" +
+		")\n" +
+		"```\n" +
+		"\n" +
+		"```sh\n" +
+		"import ( b a )\n" +
+		"```\n" +
+		"\n" +
+		"1. In a list:\n" +
+		"   ~~~~golang\n" +
+		"   package main\n" +
+		"\n" +
+		"   import (\n" +
+		"   \t\"fmt\"\n" +
+		"   \t\"os\"\n" +
+		"   )\n" +
+		"   ~~~~\n" +
+		"\n" +
+		"```go\n" +
+		"fmt.Println(\"not a file\")\n" +
+		"```\n"
+
+	src, dist, err := LoadFormatEmbedded([]byte(in), "README.md", embeddedTestConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != in {
+		t.Errorf("source changed:\n%s", src)
+	}
+	if string(dist) != out {
+		t.Errorf("got:\n%s\nwant:\n%s", dist, out)
+	}
+}
+
+func TestLoadFormatEmbeddedMarkdownUnclosedFence(t *testing.T) {
+	in := "```go\npackage main\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n"
+
+	_, dist, err := LoadFormatEmbedded([]byte(in), "README.md", embeddedTestConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(dist) != in {
+		t.Errorf("got:\n%s\nwant it unchanged", dist)
+	}
+}
+
+func TestLoadFormatEmbeddedTxtar(t *testing.T) {
+	in := "comment\n" +
+		"-- go.mod --\n" +
+		"module example.com/m\n" +
+		"-- a.go --\n" +
+		"package a\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"os\"\n" +
+		"\t\"fmt\"\n" +
+		")\n" +
+		"-- b.txt --\n" +
+		"import (\n" +
+		"\t\"os\"\n" +
+		"\t\"fmt\"\n" +
+		")\n" +
+		"-- b.go --\n" +
+		"package b\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"os\"\n" +
+		"\t\"fmt\"\n" +
+		")"
+	out := "comment\n" +
+		"-- go.mod --\n" +
+		"module example.com/m\n" +
+		"-- a.go --\n" +
+		"package a\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"fmt\"\n" +
+		"\t\"os\"\n" +
+		")\n" +
+		"-- b.txt --\n" +
+		"import (\n" +
+		"\t\"os\"\n" +
+		"\t\"fmt\"\n" +
+		")\n" +
+		"-- b.go --\n" +
+		"package b\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"fmt\"\n" +
+		"\t\"os\"\n" +
+		")\n"
+
+	src, dist, err := LoadFormatEmbedded([]byte(in), "testdata/script.txtar", embeddedTestConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != in {
+		t.Errorf("source changed:\n%s", src)
+	}
+	if string(dist) != out {
+		t.Errorf("got:\n%s\nwant:\n%s", dist, out)
+	}
+}
+
+func TestLoadFormatEmbeddedSkipsInvalidSnippets(t *testing.T) {
+	in := "-- a.go --\n" +
+		"package a\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"os\"\n" +
+		"\t\"fmt\"\n" +
+		"\n" +
+		"func {\n"
+
+	_, dist, err := LoadFormatEmbedded([]byte(in), "broken.txtar", embeddedTestConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(dist) != in {
+		t.Errorf("got:\n%s\nwant it unchanged", dist)
+	}
+}
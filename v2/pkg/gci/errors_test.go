@@ -0,0 +1,76 @@
+package gci
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/daixiang0/gci/v2/pkg/config"
+)
+
+func TestProcessFilesKeepGoing(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go": "package a\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n",
+		"b.go": "package b\nimport (\n",
+		"c.go": "broken",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.Config{KeepGoing: true}
+	var lock sync.Mutex
+	var processed []string
+	err := ProcessFiles(GoFilesInPathsGenerator([]string{dir, filepath.Join(dir, "missing")}, false), cfg, func(filePath string, _, _ []byte) error {
+		lock.Lock()
+		defer lock.Unlock()
+		processed = append(processed, filePath)
+		return nil
+	})
+
+	if want := []string{filepath.Join(dir, "a.go")}; !reflect.DeepEqual(processed, want) {
+		t.Errorf("processed %v, want %v", processed, want)
+	}
+	var paths []string
+	for _, e := range FileErrors(err) {
+		paths = append(paths, e.Path)
+	}
+	want := []string{filepath.Join(dir, "b.go"), filepath.Join(dir, "c.go"), filepath.Join(dir, "missing")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("failed files %v, want %v", paths, want)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("error %v does not wrap os.ErrNotExist", err)
+	}
+}
+
+func TestPrintErrorSummary(t *testing.T) {
+	err := errors.Join(
+		&FileError{Path: "b.go", Err: errors.New("b.go:2:10: expected ')', found 'EOF'")},
+		&FileError{Path: "dir/c.go", Err: errors.New("permission denied")},
+	)
+
+	var out strings.Builder
+	if !PrintErrorSummary(&out, err) {
+		t.Fatal("expected a summary")
+	}
+	want := "2 files could not be processed:\n" +
+		"FILE      ERROR\n" +
+		"b.go      2:10: expected ')', found 'EOF'\n" +
+		"dir/c.go  permission denied\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	if PrintErrorSummary(&out, errors.New("other")) || out.Len() != 0 {
+		t.Errorf("unexpected summary %q", out.String())
+	}
+}
@@ -0,0 +1,63 @@
+package gci
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/daixiang0/gci/v2/pkg/config"
+)
+
+// ReadFileList reads the paths listed in the file at path, "-" reads them from STDIN.
+func ReadFileList(path string) ([]string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseFileList(data), nil
+}
+
+// ParseFileList splits a list of paths at NUL bytes if there are any and at newlines otherwise.
+func ParseFileList(data []byte) []string {
+	sep := []byte{'\n'}
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = []byte{0}
+	}
+
+	var paths []string
+	for _, entry := range bytes.Split(data, sep) {
+		path := string(entry)
+		if sep[0] == '\n' {
+			path = strings.TrimSuffix(path, "\r")
+		}
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// FilterFileList drops the listed paths that do not exist or can not be formatted, with a warning for each.
+func FilterFileList(paths []string, cfg config.Config) []string {
+	var kept []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "warning: skipping listed file: %v\n", err)
+		case info.IsDir(), strings.HasSuffix(path, ".go"), cfg.FormatEmbedded && IsEmbeddingFile(path):
+			kept = append(kept, path)
+		default:
+			fmt.Fprintf(os.Stderr, "warning: skipping listed file %s: not a Go file\n", path)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,41 @@
+package gci
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/daixiang0/gci/v2/pkg/config"
+)
+
+func TestParseFileList(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"a.go\r\n\nb/c.go", []string{"a.go", "b/c.go"}},
+		{"a.go\x00with\nnewline.go\x00", []string{"a.go", "with\nnewline.go"}},
+	}
+	for _, tc := range testCases {
+		if got := ParseFileList([]byte(tc.in)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseFileList(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestFilterFileList(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	listed := []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "missing.go"), filepath.Join(dir, "notes.txt"), dir}
+
+	want := []string{filepath.Join(dir, "a.go"), dir}
+	if got := FilterFileList(listed, config.Config{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -2,9 +2,14 @@ package gci
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"go/format"
+	"io/fs"
 	"os"
+	"runtime"
+	"sort"
 	"sync"
 
 	"golang.org/x/sync/errgroup"
@@ -12,6 +17,8 @@ import (
 	"github.com/daixiang0/gci/v2/internal/imports"
 	"github.com/daixiang0/gci/v2/pkg/config"
 	"github.com/daixiang0/gci/v2/pkg/parse"
+	"github.com/daixiang0/gci/v2/pkg/section"
+	"github.com/daixiang0/gci/v2/pkg/specificity"
 )
 
 func PrintFormattedFiles(paths []string, cfg config.Config) error {
@@ -26,7 +33,7 @@ func WriteFormattedFiles(paths []string, cfg config.Config) error {
 		if bytes.Equal(unmodifiedFile, formattedFile) {
 			return nil
 		}
-		return os.WriteFile(filePath, formattedFile, 0o644)
+		return WriteFileAtomic(filePath, formattedFile)
 	})
 }
 
@@ -41,14 +48,18 @@ func ListUnFormattedFiles(paths []string, cfg config.Config) error {
 }
 
 func DiffFormattedFiles(paths []string, cfg config.Config) error {
+	return DiffFormattedFilesWithOptions(paths, cfg, DefaultDiffOptions())
+}
+
+func DiffFormattedFilesWithOptions(paths []string, cfg config.Config, opts DiffOptions) error {
 	return processStdInAndGoFilesInPaths(paths, cfg, func(filePath string, unmodifiedFile, formattedFile []byte) error {
-		return diffFormattedFiles(filePath, unmodifiedFile, formattedFile)
+		return diffFormattedFiles(filePath, unmodifiedFile, formattedFile, cfg, opts)
 	})
 }
 
 func DiffFormattedFilesToArray(paths []string, cfg config.Config, diffs *[]string, lock *sync.Mutex) error {
 	return processStdInAndGoFilesInPaths(paths, cfg, func(filePath string, unmodifiedFile, formattedFile []byte) error {
-		diff, err := diffToString(filePath, unmodifiedFile, formattedFile)
+		diff, err := diffToString(filePath, unmodifiedFile, formattedFile, cfg, DefaultDiffOptions())
 		if err != nil {
 			return err
 		}
@@ -62,33 +73,88 @@ func DiffFormattedFilesToArray(paths []string, cfg config.Config, diffs *[]strin
 type fileFormattingFunc func(filePath string, unmodifiedFile, formattedFile []byte) error
 
 func processStdInAndGoFilesInPaths(paths []string, cfg config.Config, fileFunc fileFormattingFunc) error {
-	return ProcessFiles(CombineGenerators(StdInGenerator, GoFilesInPathsGenerator(paths, cfg.SkipVendor)), cfg, fileFunc)
+	if cfg.NoStdin {
+		return processGoFilesInPaths(paths, cfg, fileFunc)
+	}
+	return ProcessFiles(CombineGenerators(StdInGeneratorWithPath(cfg.StdinFilename), SourceFilesInPathsGenerator(paths, cfg.SkipVendor, cfg.FormatEmbedded)), cfg, fileFunc)
 }
 
 func processGoFilesInPaths(paths []string, cfg config.Config, fileFunc fileFormattingFunc) error {
-	return ProcessFiles(GoFilesInPathsGenerator(paths, cfg.SkipVendor), cfg, fileFunc)
+	return ProcessFiles(SourceFilesInPathsGenerator(paths, cfg.SkipVendor, cfg.FormatEmbedded), cfg, fileFunc)
+}
+
+type fileResult struct {
+	path                          string
+	unmodifiedFile, formattedFile []byte
+	err                           error
 }
 
+// ProcessFiles formats the files in parallel, but passes them to fileFunc in the order of the generator.
 func ProcessFiles(fileGenerator FileGeneratorFunc, cfg config.Config, fileFunc fileFormattingFunc) error {
-	var taskGroup errgroup.Group
-	files, err := fileGenerator()
-	if err != nil {
-		return err
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the capacity of pending limits how many results are waiting for their turn
+	pending := make(chan chan fileResult, jobs(cfg))
+	go func() {
+		defer close(pending)
+		var workers errgroup.Group
+		workers.SetLimit(jobs(cfg))
+		defer workers.Wait()
+
+		fileGenerator(func(file FileObj, err error) bool {
+			result := make(chan fileResult, 1)
+			select {
+			case pending <- result:
+			case <-ctx.Done():
+				return false
+			}
+			if err != nil {
+				result <- fileResult{path: errorPath(err), err: err}
+				return true
+			}
+			workers.Go(func() error {
+				unmodifiedFile, formattedFile, err := LoadFormatGoFile(file, cfg)
+				result <- fileResult{file.Path, unmodifiedFile, formattedFile, err}
+				return nil
+			})
+			return true
+		})
+	}()
+
+	var errs []error
+	for result := range pending {
+		r := <-result
+		if r.err == nil {
+			r.err = fileFunc(r.path, r.unmodifiedFile, r.formattedFile)
+		}
+		if r.err == nil {
+			continue
+		}
+		if !cfg.KeepGoing {
+			cancel()
+			for range pending {
+			}
+			return r.err
+		}
+		errs = append(errs, &FileError{Path: r.path, Err: r.err})
 	}
-	for _, file := range files {
-		taskGroup.Go(processingFunc(file, cfg, fileFunc))
+	return errors.Join(errs...)
+}
+
+func errorPath(err error) string {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Path
 	}
-	return taskGroup.Wait()
+	return ""
 }
 
-func processingFunc(file FileObj, cfg config.Config, formattingFunc fileFormattingFunc) func() error {
-	return func() error {
-		unmodifiedFile, formattedFile, err := LoadFormatGoFile(file, cfg)
-		if err != nil {
-			return err
-		}
-		return formattingFunc(file.Path, unmodifiedFile, formattedFile)
+func jobs(cfg config.Config) int {
+	if cfg.Jobs < 1 {
+		return runtime.GOMAXPROCS(0)
 	}
+	return cfg.Jobs
 }
 
 func LoadFormatGoFile(file FileObj, cfg config.Config) (src, dist []byte, err error) {
@@ -97,22 +163,270 @@ func LoadFormatGoFile(file FileObj, cfg config.Config) (src, dist []byte, err er
 		return nil, nil, err
 	}
 
-	return LoadFormat(src, file.Path, cfg)
+	// files found in paths are already filtered, but STDIN may belong to a vendored file
+	if cfg.SkipVendor && file.IsStdin && isVendored(file.Path) {
+		return src, src, nil
+	}
+
+	embedded := cfg.FormatEmbedded && IsEmbeddingFile(file.Path)
+	// the same content may be formatted differently as Go file and as Markdown or txtar file
+	kind := []byte("go")
+	if embedded {
+		kind = []byte("embedded")
+	}
+	var entry [][]byte
+	if cfg.Cache != nil {
+		// files without module fail while formatting, if their imports need it
+		entry, _ = cacheEntry(file.Path, kind, src, cfg)
+	}
+	if entry != nil && cfg.Cache.Has(entry...) {
+		return src, src, nil
+	}
+
+	if embedded {
+		src, dist, err = LoadFormatEmbedded(src, file.Path, cfg)
+	} else {
+		src, dist, err = LoadFormat(src, file.Path, cfg)
+	}
+	if err == nil && entry != nil && bytes.Equal(src, dist) {
+		// a failed cache write only costs time in the next run
+		_ = cfg.Cache.Add(entry...)
+	}
+	return src, dist, err
 }
 
 func LoadFormat(in []byte, path string, cfg config.Config) (src, dist []byte, err error) {
 	src = in
 
-	if cfg.SkipGenerated && parse.IsGeneratedFileByComment(string(src)) {
+	if reason, generated := isGenerated(src, path, cfg); generated {
+		if cfg.Debug {
+			fmt.Fprintf(os.Stderr, "debug: skipping generated file %s: %s\n", path, reason)
+		}
+		return src, src, nil
+	}
+
+	normalized, layout := normalize(src)
+	ignored, err := parse.IsIgnored(normalized, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ignored {
 		return src, src, nil
 	}
+	dist, err = formatImports(normalized, path, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bytes.Equal(normalized, dist) && (cfg.LineEndings == "" || cfg.LineEndings == config.LineEndingsPreserve) {
+		return src, src, nil
+	}
+
+	return src, layout.restore(dist, cfg.LineEndings), nil
+}
+
+// isGenerated reports whether src is a generated file that SkipGenerated skips, and why.
+func isGenerated(src []byte, path string, cfg config.Config) (string, bool) {
+	if !cfg.SkipGenerated {
+		return "", false
+	}
+	if cfg.GeneratedFiles == config.GeneratedFilesStrict {
+		return parse.GeneratedByHeader(src, path)
+	}
+	return parse.GeneratedByMarker(string(src))
+}
+
+func formatImports(src []byte, path string, cfg config.Config) ([]byte, error) {
+	// imports that can not be renamed without a conflict are shown with the diff
+	src, _, _, err := parse.RewriteAliases(src, path, cfg.Aliases)
+	if err != nil {
+		return nil, err
+	}
+	// import "C" needs a declaration of its own to keep its preamble when the other imports are sorted
+	src, err = parse.SplitCgoImports(src, path)
+	if err != nil {
+		return nil, err
+	}
+	// all import declarations end up in the first one, unless they are kept apart on purpose
+	if !cfg.NoMerge {
+		src, err = parse.MergeImportDecls(src, path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Dedupe {
+		src, _, err = parse.DedupeImports(src, path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.NoMerge {
+		return formatImportDecls(src, path, cfg)
+	}
+	return formatImportDecl(src, path, cfg)
+}
 
-	_, _, _, _, _, err = parse.ParseFile(src, path)
+// formatImportDecls formats every import declaration of src on its own, so each keeps its pinned segments.
+func formatImportDecls(src []byte, path string, cfg config.Config) ([]byte, error) {
+	decls, err := parse.ImportDecls(src, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(decls) <= 1 {
+		return formatImportDecl(src, path, cfg)
+	}
+
+	const header = "package p\n\n"
+	dist := src
+	// from the last to the first declaration, so the offsets stay valid
+	for i := len(decls) - 1; i >= 0; i-- {
+		decl := decls[i]
+		formatted, err := formatImportDecl([]byte(header+string(src[decl.Start:decl.End])+"\n"), path, cfg)
+		if err != nil {
+			return nil, err
+		}
+		formatted = bytes.TrimSuffix(bytes.TrimPrefix(formatted, []byte(header)), []byte("\n"))
+		dist = append(dist[:decl.Start:decl.Start], append(formatted, dist[decl.End:]...)...)
+	}
+	// gofmt would sort the imports of the pinned segments
+	dist, err = parse.FormatKeepingImportOrder(dist, path)
+	if err != nil {
+		return nil, err
+	}
+	return placeCgoImports(dist, path, cfg)
+}
+
+// formatImportDecl formats src, which has one import declaration besides import "C", around its pinned segments.
+func formatImportDecl(src []byte, path string, cfg config.Config) ([]byte, error) {
+	segments, err := parse.PinnedSegments(src, path)
+	if err != nil {
+		return nil, err
+	}
+	if segments != nil {
+		return formatAroundPinned(src, path, cfg, segments)
+	}
+	return processImports(src, path, cfg)
+}
+
+// formatAroundPinned sorts the imports outside of the pinned segments of the import declaration as one group and puts
+// them back into the unpinned segments, as many as each held before, while the imports between //gci:off and //gci:on
+// and the ones marked with //nolint:gci stay where they are.
+func formatAroundPinned(src []byte, path string, cfg config.Config, segments []parse.ImportSegment) ([]byte, error) {
+	const header = "package p\n\nimport (\n"
+	var unpinned []byte
+	var slots []int
+	for _, segment := range segments {
+		if segment.Pinned {
+			continue
+		}
+		text := src[segment.Start:segment.End]
+		entries, err := importEntries([]byte(header+string(text)+")\n"), path)
+		if err != nil {
+			return nil, err
+		}
+		slots = append(slots, len(entries))
+		unpinned = append(unpinned, text...)
+	}
+	formatted, err := processImports([]byte(header+string(unpinned)+")\n"), path, cfg)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := importEntries(formatted, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var body []byte
+	for _, segment := range segments {
+		var text []byte
+		if segment.Pinned {
+			text = bytes.TrimSpace(src[segment.Start:segment.End])
+		} else {
+			n := min(slots[0], len(entries))
+			if len(slots) == 1 {
+				// the last segment takes what is left
+				n = len(entries)
+			}
+			for i, e := range entries[:n] {
+				if i > 0 {
+					text = append(text, '\n')
+					if e.newSection {
+						text = append(text, '\n')
+					}
+					text = append(text, '\t')
+				}
+				text = append(text, e.text...)
+			}
+			entries, slots = entries[n:], slots[1:]
+		}
+		if len(text) == 0 {
+			continue
+		}
+		if len(body) > 0 {
+			body = append(body, '\n')
+		}
+		body = append(body, '\t')
+		body = append(body, text...)
+		body = append(body, '\n')
+	}
+
+	dist := append(src[:segments[0].Start:segments[0].Start], append(body, src[segments[len(segments)-1].End:]...)...)
+	// gofmt would sort the imports of the pinned segments
+	return parse.FormatKeepingImportOrder(dist, path)
+}
+
+// importEntry is the text of an import of a formatted import declaration, with its comments.
+type importEntry struct {
+	text []byte
+	// newSection is set if a blank line separates the import from the one in front of it
+	newSection bool
+}
+
+// importEntries returns the imports of src in the order they are in.
+func importEntries(src []byte, path string) ([]importEntry, error) {
+	imports, _, _, _, _, err := parse.ParseFile(src, path)
 	if err != nil {
 		if errors.Is(err, parse.NoImportError{}) {
-			return src, src, nil
+			return nil, nil
+		}
+		return nil, err
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		return imports[i].Start < imports[j].Start
+	})
+	entries := make([]importEntry, len(imports))
+	end := 0
+	for i, imp := range imports {
+		// the range of an import ends after its linebreak
+		entries[i].text = bytes.TrimRight(src[imp.Start:imp.End], "\n")
+		if i > 0 {
+			entries[i].newSection = bytes.Count(src[end:imp.Start], []byte{'\n'}) > 1
+		}
+		end = imp.Start + len(entries[i].text)
+	}
+	return entries, nil
+}
+
+// processImports sorts the imports of src into their sections.
+func processImports(src []byte, path string, cfg config.Config) ([]byte, error) {
+	_, _, _, _, _, err := parse.ParseFile(src, path)
+	if err != nil {
+		if errors.Is(err, parse.NoImportError{}) {
+			return src, nil
+		}
+		return nil, err
+	}
+	// localmodule sections match the module the file belongs to
+	cfg.Sections, err = cfg.Sections.ForFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []string
+	if len(cfg.SubgroupByModule) > 0 {
+		modules, err = section.RequiredModules(path)
+		if err != nil {
+			return nil, err
 		}
-		return nil, nil, err
 	}
 
 	opts := &imports.Options{
@@ -121,12 +435,50 @@ func LoadFormat(in []byte, path string, cfg config.Config) (src, dist []byte, er
 		TabIndent:  true,
 		TabWidth:   8,
 		FormatOnly: true,
+		Modules:    modules,
 	}
 
-	dist, err = imports.Process(path, src, opts)
+	dist, err := imports.Process(path, src, opts)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	return placeCgoImports(dist, path, cfg)
+}
+
+// placeCgoImports moves the import "C" declarations, which imports.Process puts in front of the other imports, to the
+// cgo section.
+func placeCgoImports(src []byte, path string, cfg config.Config) ([]byte, error) {
+	sections := cfg.Sections
+	cgoIndex := -1
+	for i, s := range sections {
+		if _, ok := s.(section.Cgo); ok {
+			cgoIndex = i
+		}
+	}
+	if cgoIndex < 0 {
+		return src, nil
 	}
+	moved, err := parse.MoveCgoImportDecls(src, path, func(imp *parse.GciImports) bool {
+		best := -1
+		var bestSpecificity specificity.MatchSpecificity = specificity.MisMatch{}
+		for i, s := range sections {
+			if m := s.MatchSpecificity(imp); m.IsMoreSpecific(bestSpecificity) {
+				best, bestSpecificity = i, m
+			}
+		}
+		return best >= 0 && best < cgoIndex
+	})
+	if err != nil || bytes.Equal(moved, src) {
+		return src, err
+	}
+	return gofmt(moved, path, cfg)
+}
 
-	return src, dist, nil
+// gofmt formats src like gofmt, which sorts consecutive imports by path. Sections with another sort mode keep the
+// order of their imports.
+func gofmt(src []byte, path string, cfg config.Config) ([]byte, error) {
+	if !cfg.SortsLexically() {
+		return parse.FormatKeepingImportOrder(src, path)
+	}
+	return format.Source(src)
 }
@@ -35,6 +35,63 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestRunKeepsLineEndingsAndBOM(t *testing.T) {
+	toCRLF := func(s string) string {
+		return strings.ReplaceAll(s, "\n", "\r\n")
+	}
+	const bom = "\uFEFF"
+
+	for i := range testCases {
+		t.Run(fmt.Sprintf("run case: %s", testCases[i].name), func(t *testing.T) {
+			cfg, err := config.ParseConfig(testCases[i].config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, got, err := LoadFormat([]byte(bom+toCRLF(testCases[i].in)), "", *cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := bom + toCRLF(testCases[i].out); string(got) != want {
+				t.Errorf("output mismatch:\ngot:\n%q\nwant:\n%q", string(got), want)
+			}
+
+			_, again, err := LoadFormat(got, "", *cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(got) {
+				t.Errorf("formatting is not stable:\ngot:\n%q\nwant:\n%q", string(again), string(got))
+			}
+		})
+	}
+}
+
+func TestRunWithLineEndings(t *testing.T) {
+	crlfIn := "package main\r\n\r\nimport (\r\n\t\"os\"\r\n\t\"fmt\"\r\n)\r\n"
+	lfOut := "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n"
+
+	for _, tt := range []struct {
+		lineEndings config.LineEndings
+		in, out     string
+	}{
+		{config.LineEndingsPreserve, crlfIn, strings.ReplaceAll(lfOut, "\n", "\r\n")},
+		{config.LineEndingsLF, crlfIn, lfOut},
+		{config.LineEndingsCRLF, lfOut, strings.ReplaceAll(lfOut, "\n", "\r\n")},
+	} {
+		t.Run(string(tt.lineEndings), func(t *testing.T) {
+			cfg := config.Config{Sections: section.DefaultSections(), LineEndings: tt.lineEndings}
+			_, got, err := LoadFormat([]byte(tt.in), "", cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.out {
+				t.Errorf("output mismatch:\ngot:\n%q\nwant:\n%q", string(got), tt.out)
+			}
+		})
+	}
+}
+
 func chdir(t *testing.T, dir string) {
 	oldWd, err := os.Getwd()
 	if err != nil {
@@ -60,40 +117,41 @@ func readConfig(t *testing.T, configPath string) *config.Config {
 	return cfg
 }
 
-func TestRunWithLocalModule(t *testing.T) {
-	tests := []struct {
-		name      string
-		moduleDir string
-		testedFiles []string
-	}{
-		{
-			name:      `default module test case`,
-			moduleDir: filepath.Join("testdata", "module"),
-			testedFiles: []string{
-				"main.go",
-				filepath.Join("internal", "foo", "lib.go"),
-			},
+var localModuleTests = []struct {
+	name      string
+	moduleDir string
+	testedFiles []string
+}{
+	{
+		name:      `default module test case`,
+		moduleDir: filepath.Join("testdata", "module"),
+		testedFiles: []string{
+			"main.go",
+			filepath.Join("internal", "foo", "lib.go"),
 		},
-		{
-			name:      `canonical module without go sources in root dir`,
-			moduleDir: filepath.Join("testdata", "module_canonical"),
-			testedFiles: []string{
-				filepath.Join("cmd", "client", "main.go"),
-				filepath.Join("cmd", "server", "main.go"),
-				filepath.Join("internal", "foo", "lib.go"),
-			},
+	},
+	{
+		name:      `canonical module without go sources in root dir`,
+		moduleDir: filepath.Join("testdata", "module_canonical"),
+		testedFiles: []string{
+			filepath.Join("cmd", "client", "main.go"),
+			filepath.Join("cmd", "server", "main.go"),
+			filepath.Join("internal", "foo", "lib.go"),
 		},
-		{
-			name:      `non-canonical module without go sources in root dir`,
-			moduleDir: filepath.Join("testdata", "module_noncanonical"),
-			testedFiles: []string{
-				filepath.Join("cmd", "client", "main.go"),
-				filepath.Join("cmd", "server", "main.go"),
-				filepath.Join("internal", "foo", "lib.go"),
-			},
+	},
+	{
+		name:      `non-canonical module without go sources in root dir`,
+		moduleDir: filepath.Join("testdata", "module_noncanonical"),
+		testedFiles: []string{
+			filepath.Join("cmd", "client", "main.go"),
+			filepath.Join("cmd", "server", "main.go"),
+			filepath.Join("internal", "foo", "lib.go"),
 		},
-	}
-	for _, tt := range tests {
+	},
+}
+
+func TestRunWithLocalModule(t *testing.T) {
+	for _, tt := range localModuleTests {
 		t.Run(tt.name, func(t *testing.T) {
 			chdir(t, tt.moduleDir)
 			cfg := readConfig(t, "config.yaml")
@@ -125,12 +183,52 @@ func TestRunWithLocalModule(t *testing.T) {
 	}
 }
 
+func TestRunWithLocalModuleFromOtherDirectory(t *testing.T) {
+	// every file uses the module it belongs to, regardless of the working directory
+	cfg, err := config.ParseConfig("sections:\n  - Standard\n  - Default\n  - LocalModule\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range localModuleTests {
+		for _, path := range tt.testedFiles {
+			path = filepath.Join(tt.moduleDir, path)
+			t.Run(path, func(t *testing.T) {
+				expected, err := os.ReadFile(strings.TrimSuffix(path, ".go") + ".out.go")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				fileObj := FileObj{
+					Path: path,
+					Load: func() ([]byte, error) {
+						return os.ReadFile(path)
+					},
+				}
+				_, got, err := LoadFormatGoFile(fileObj, *cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != string(expected) {
+					t.Errorf("got:\n%s\nwant:\n%s", got, expected)
+				}
+			})
+		}
+	}
+}
+
 func TestRunWithLocalModuleWithPackageLoadFailure(t *testing.T) {
 	dir := t.TempDir()
 	configContent := "sections:\n  - LocalModule\n"
 
-	chdir(t, dir)
-	_, err := config.ParseConfig(configContent)
+	cfg, err := config.ParseConfig(configContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "main.go")
+	src := []byte("package main\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n")
+	_, _, err = LoadFormatGoFile(FileObj{Path: path, Load: func() ([]byte, error) { return src, nil }}, *cfg)
 	if err == nil {
 		t.Fatal("expected error for missing go.mod")
 	}
@@ -180,3 +278,146 @@ func main() {}
 		t.Error("expected generated file to be skipped")
 	}
 }
+
+func TestSkipGeneratedFileStrict(t *testing.T) {
+	// the markers only appear in a string, which the lax detection does not tell apart from a comment
+	src := `package main
+
+import (
+	"os"
+	"fmt"
+)
+
+const warning = "do not edit this file by hand"
+`
+	testCases := []struct {
+		generatedFiles config.GeneratedFiles
+		skipped        bool
+	}{
+		// lax by default, like before there was a choice
+		{"", true},
+		{config.GeneratedFilesStrict, false},
+		{config.GeneratedFilesLax, true},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.generatedFiles), func(t *testing.T) {
+			cfg := config.Config{
+				BoolConfig: config.BoolConfig{
+					SkipGenerated: true,
+				},
+				Sections:       section.DefaultSections(),
+				GeneratedFiles: tc.generatedFiles,
+			}
+
+			old, new, err := LoadFormat([]byte(src), "test.go", cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if skipped := string(old) == string(new); skipped != tc.skipped {
+				t.Errorf("expected skipped %v, got %v", tc.skipped, skipped)
+			}
+		})
+	}
+}
+
+func TestProcessFilesKeepsGeneratorOrder(t *testing.T) {
+	dir := t.TempDir()
+	var want []string
+	for d := 0; d < 5; d++ {
+		for f := 0; f < 20; f++ {
+			path := filepath.Join(dir, fmt.Sprintf("pkg%d", d), fmt.Sprintf("file%02d.go", f))
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			// files of different sizes take different times to format
+			imports := strings.Repeat("\t\"os\"\n\t\"fmt\"\n", f%7+1)
+			if err := os.WriteFile(path, []byte("package p\n\nimport (\n"+imports+")\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, path)
+		}
+	}
+
+	cfg, err := config.ParseConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Jobs = 8
+	for i := 0; i < 50; i++ {
+		var got []string
+		err := ProcessFiles(GoFilesInPathsGenerator([]string{dir}, false), *cfg, func(filePath string, _, _ []byte) error {
+			got = append(got, filePath)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("run %d processed the files in a different order:\n%s", i, strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestSubgroupByModule(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(`module example.com/app
+
+go 1.22
+
+require (
+	github.com/aws/aws-sdk-go v1.55.0
+	github.com/stretchr/testify v1.9.0
+	go.uber.org/zap v1.27.0
+)
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// the modules that are not required are grouped by their host or repository, never with another module
+	src := `package main
+
+import (
+	"os"
+	"go.uber.org/zap"
+	"github.com/stretchr/testify/assert"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"go.uber.org/multierr"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/smithy-go"
+	"github.com/zed/x"
+	"gitlab.com/group/project"
+)
+`
+	want := `package main
+
+import (
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/aws/smithy-go"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zed/x"
+
+	"gitlab.com/group/project"
+
+	"go.uber.org/multierr"
+
+	"go.uber.org/zap"
+)
+`
+
+	cfg, err := config.ParseConfig("subgroupByModule: [default]\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, got, err := LoadFormat([]byte(src), filepath.Join(dir, "main.go"), *cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("output mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -13,12 +13,19 @@ type FileObj struct {
 	IsStdin bool
 }
 
-type FileGeneratorFunc func() ([]FileObj, error)
+// FileGeneratorFunc streams files to yield until it returns false.
+// Errors that occur while searching for files are yielded as well.
+type FileGeneratorFunc func(yield func(FileObj, error) bool)
 
 func GoFilesInPathsGenerator(paths []string, skipVendor bool) FileGeneratorFunc {
-	return func() ([]FileObj, error) {
-		var files []FileObj
+	return SourceFilesInPathsGenerator(paths, skipVendor, false)
+}
+
+// SourceFilesInPathsGenerator finds Go files and, if embedded is set, Markdown and txtar files which may contain Go files.
+func SourceFilesInPathsGenerator(paths []string, skipVendor, embedded bool) FileGeneratorFunc {
+	return func(yield func(FileObj, error) bool) {
 		for _, path := range paths {
+			stopped := false
 			err := filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
 				if err != nil {
 					return err
@@ -29,47 +36,79 @@ func GoFilesInPathsGenerator(paths []string, skipVendor bool) FileGeneratorFunc
 					}
 					return nil
 				}
-				if strings.HasSuffix(filePath, ".go") && !strings.HasPrefix(filepath.Base(filePath), ".") {
-					files = append(files, FileObj{
+				isSource := strings.HasSuffix(filePath, ".go") || (embedded && IsEmbeddingFile(filePath))
+				if isSource && !strings.HasPrefix(filepath.Base(filePath), ".") {
+					stopped = !yield(FileObj{
 						Path: filePath,
 						Load: func() ([]byte, error) {
 							return os.ReadFile(filePath)
 						},
-					})
+					}, nil)
+					if stopped {
+						return filepath.SkipAll
+					}
 				}
 				return nil
 			})
-			if err != nil {
-				return nil, err
+			if stopped {
+				return
+			}
+			if err != nil && !yield(FileObj{}, err) {
+				return
 			}
 		}
-		return files, nil
 	}
 }
 
-func StdInGenerator() ([]FileObj, error) {
-	stdinFilePath := "<standard input>"
-	return []FileObj{
-		{
-			Path:    stdinFilePath,
+func IsEmbeddingFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".txtar":
+		return true
+	}
+	return false
+}
+
+func StdInGenerator(yield func(FileObj, error) bool) {
+	StdInGeneratorWithPath("")(yield)
+}
+
+// StdInGeneratorWithPath reads the content of a file from STDIN, but reports path as its location.
+func StdInGeneratorWithPath(path string) FileGeneratorFunc {
+	if path == "" {
+		path = "<standard input>"
+	}
+	return func(yield func(FileObj, error) bool) {
+		yield(FileObj{
+			Path:    path,
 			IsStdin: true,
 			Load: func() ([]byte, error) {
 				return io.ReadAll(os.Stdin)
 			},
-		},
-	}, nil
+		}, nil)
+	}
+}
+
+// isVendored reports whether the file is inside a vendor directory.
+func isVendored(path string) bool {
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(path)), "/") {
+		if dir == "vendor" {
+			return true
+		}
+	}
+	return false
 }
 
 func CombineGenerators(generators ...FileGeneratorFunc) FileGeneratorFunc {
-	return func() ([]FileObj, error) {
-		var allFiles []FileObj
+	return func(yield func(FileObj, error) bool) {
+		stopped := false
 		for _, gen := range generators {
-			files, err := gen()
-			if err != nil {
-				return nil, err
+			gen(func(file FileObj, err error) bool {
+				stopped = !yield(file, err)
+				return !stopped
+			})
+			if stopped {
+				return
 			}
-			allFiles = append(allFiles, files...)
 		}
-		return allFiles, nil
 	}
 }
@@ -0,0 +1,20 @@
+package gci
+
+import "testing"
+
+func TestIsVendored(t *testing.T) {
+	testCases := map[string]bool{
+		"a.go":                 false,
+		"vendor/a/a.go":        true,
+		"pkg/vendor/a.go":      true,
+		"pkg/vendored/a.go":    false,
+		"vendor.go":            false,
+		"<standard input>":     false,
+		"/abs/vendor/x/y/z.go": true,
+	}
+	for path, want := range testCases {
+		if got := isVendored(path); got != want {
+			t.Errorf("isVendored(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package gci
+
+import (
+	"bytes"
+
+	"github.com/daixiang0/gci/v2/pkg/config"
+)
+
+var (
+	bom  = []byte{0xEF, 0xBB, 0xBF}
+	lf   = []byte{'\n'}
+	crlf = []byte{'\r', '\n'}
+)
+
+type textLayout struct {
+	bom  bool
+	crlf bool
+}
+
+// normalize strips the BOM and CRLF line endings; a file counts as CRLF when most lines use them.
+func normalize(src []byte) ([]byte, textLayout) {
+	var layout textLayout
+	if bytes.HasPrefix(src, bom) {
+		layout.bom = true
+		src = src[len(bom):]
+	}
+	crlfCount := bytes.Count(src, crlf)
+	layout.crlf = crlfCount > 0 && crlfCount*2 >= bytes.Count(src, lf)
+	return bytes.ReplaceAll(src, crlf, lf), layout
+}
+
+func (l textLayout) restore(src []byte, lineEndings config.LineEndings) []byte {
+	useCRLF := l.crlf
+	switch lineEndings {
+	case config.LineEndingsLF:
+		useCRLF = false
+	case config.LineEndingsCRLF:
+		useCRLF = true
+	}
+	if useCRLF {
+		src = bytes.ReplaceAll(src, lf, crlf)
+	}
+	if l.bom {
+		src = append(append([]byte{}, bom...), src...)
+	}
+	return src
+}
@@ -1216,6 +1216,578 @@ import (
 	"fmt"
 	"net"
 )
+`,
+	},
+	{
+		"merge-imports-around-declarations",
+		commonConfig,
+		`package main
+
+// main imports
+import (
+	"os"
+
+	"github.com/daixiang0/test" // test
+)
+
+const version = "v1"
+
+// formatting
+import "fmt"
+
+var debug = false
+
+import (
+	// strings is for the name
+	"strings"
+)
+
+func main() {
+}
+`,
+		`package main
+
+// main imports
+import (
+	// formatting
+	"fmt"
+	"os"
+	// strings is for the name
+	"strings"
+
+	"github.com/daixiang0/test" // test
+)
+
+const version = "v1"
+
+var debug = false
+
+func main() {
+}
+`,
+	},
+	{
+		"no-merge",
+		`sections:
+  - Standard
+  - Default
+noMerge: true
+`,
+		`package main
+
+import (
+	"github.com/daixiang0/test"
+	"os"
+)
+
+// main imports
+import (
+	"strings"
+	"fmt"
+)
+`,
+		`package main
+
+import (
+	"os"
+
+	"github.com/daixiang0/test"
+)
+
+// main imports
+import (
+	"fmt"
+	"strings"
+)
+`,
+	},
+	{
+		"no-merge-gci-off-region",
+		`sections:
+  - Standard
+  - Default
+noMerge: true
+`,
+		`package main
+
+import (
+	"os"
+	//gci:off
+	"strings"
+	"bytes"
+	//gci:on
+	"fmt"
+)
+
+import (
+	"github.com/daixiang0/gci/b"
+	"github.com/daixiang0/gci/a" //nolint:gci
+	"errors"
+)
+`,
+		`package main
+
+import (
+	"fmt"
+
+	//gci:off
+	"strings"
+	"bytes"
+	//gci:on
+
+	"os"
+)
+
+import (
+	"errors"
+
+	"github.com/daixiang0/gci/a" //nolint:gci
+
+	"github.com/daixiang0/gci/b"
+)
+`,
+	},
+	{
+		"dedupe",
+		`sections:
+  - Standard
+  - Default
+dedupe: true
+`,
+		`package main
+
+import (
+	// formatting
+	"fmt"
+	yaml "gopkg.in/yaml.v3"
+	fmt "fmt" // printing
+	f "fmt"
+	"gopkg.in/yaml.v3"
+	"os" // files
+	// the doc of a duplicate
+	"os"
+)
+`,
+		`package main
+
+import (
+	// formatting
+	"fmt" // printing
+	f "fmt"
+	// the doc of a duplicate
+	"os" // files
+
+	"gopkg.in/yaml.v3"
+)
+`,
+	},
+	{
+		"dedupe-nolint",
+		`sections:
+  - Standard
+  - Default
+dedupe: true
+`,
+		`package main
+
+import (
+	"os"
+	"fmt"
+	"fmt"
+	yaml "gopkg.in/yaml.v3" //nolint:gci
+)
+`,
+		`package main
+
+import (
+	"fmt"
+	"os"
+
+	yaml "gopkg.in/yaml.v3" //nolint:gci
+)
+`,
+	},
+	{
+		"canonical-aliases",
+		`sections:
+  - Standard
+  - Default
+aliases:
+  k8s.io/apimachinery/pkg/apis/meta/v1: metav1
+  k8s.io/api/core/v1: corev1
+  k8s.io/api/apps/v1: appsv1
+`,
+		`package main
+
+import (
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/api/core/v1"
+	apps "k8s.io/api/apps/v1"
+)
+
+func pod(appsv1 string) v1.Pod {
+	objectMeta := meta.ObjectMeta{Name: appsv1}
+	_ = apps.Deployment{}
+	return v1.Pod{ObjectMeta: objectMeta}
+}
+`,
+		`package main
+
+import (
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func pod(appsv1 string) corev1.Pod {
+	objectMeta := metav1.ObjectMeta{Name: appsv1}
+	_ = apps.Deployment{}
+	return corev1.Pod{ObjectMeta: objectMeta}
+}
+`,
+	},
+	{
+		"canonical-aliases-unknown-name",
+		`sections:
+  - Standard
+  - Default
+aliases:
+  github.com/hashicorp/go-multierror: merr
+  k8s.io/api/core/v1: corev1
+`,
+		`package main
+
+import (
+	"github.com/hashicorp/go-multierror"
+	"k8s.io/api/core/v1"
+)
+
+func validate(pod v1.Pod) error {
+	return multierror.Append(nil, nil)
+}
+`,
+		`package main
+
+import (
+	"github.com/hashicorp/go-multierror"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func validate(pod corev1.Pod) error {
+	return multierror.Append(nil, nil)
+}
+`,
+	},
+	{
+		"gci-off-region",
+
+		commonConfig,
+
+		`package main
+
+import (
+	"os"
+	"github.com/daixiang0/gci/b"
+	//gci:off
+	_ "github.com/daixiang0/gci/mysql"
+
+	_ "github.com/daixiang0/gci/cockroach"
+	//gci:on
+	"fmt"
+	"github.com/daixiang0/gci/a"
+	"strings" //nolint:gci
+	"errors"
+)
+`,
+		`package main
+
+import (
+	"errors"
+	"fmt"
+
+	//gci:off
+	_ "github.com/daixiang0/gci/mysql"
+
+	_ "github.com/daixiang0/gci/cockroach"
+	//gci:on
+
+	"os"
+
+	"github.com/daixiang0/gci/a"
+
+	"strings" //nolint:gci
+
+	"github.com/daixiang0/gci/b"
+)
+`,
+	},
+	{
+		"gci-off-region-keeps-order",
+
+		commonConfig,
+
+		`package main
+
+import (
+	"os"
+	//gci:off
+	_ "github.com/daixiang0/gci/z"
+	_ "github.com/daixiang0/gci/a"
+	//gci:on
+	"fmt"
+)
+`,
+		`package main
+
+import (
+	"fmt"
+
+	//gci:off
+	_ "github.com/daixiang0/gci/z"
+	_ "github.com/daixiang0/gci/a"
+	//gci:on
+
+	"os"
+)
+`,
+	},
+	{
+		"nolint-import-between-sections",
+
+		commonConfig,
+
+		`package main
+
+import (
+	"github.com/daixiang0/gci/b"
+	"os"
+	"strings" //nolint:gci
+	"github.com/daixiang0/gci/a"
+	"fmt"
+)
+`,
+		`package main
+
+import (
+	"fmt"
+	"os"
+
+	"strings" //nolint:gci
+
+	"github.com/daixiang0/gci/a"
+	"github.com/daixiang0/gci/b"
+)
+`,
+	},
+	{
+		"gci-ignore",
+
+		commonConfig,
+
+		`package main
+
+//gci:ignore
+
+import (
+	"os"
+	"github.com/daixiang0/gci/b"
+	"fmt"
+)
+`,
+		`package main
+
+//gci:ignore
+
+import (
+	"os"
+	"github.com/daixiang0/gci/b"
+	"fmt"
+)
+`,
+	},
+	{
+		"cgo-inside-block",
+
+		commonConfig,
+
+		`package main
+
+import (
+	"os"
+	// #include <stdio.h>
+	"C"
+	"fmt"
+)
+`,
+		`package main
+
+// #include <stdio.h>
+import "C"
+
+import (
+	"fmt"
+	"os"
+)
+`,
+	},
+	{
+		"cgo-section",
+
+		`sections:
+  - Standard
+  - Cgo
+  - Default
+customOrder: true
+`,
+
+		`package main
+
+// #include <stdio.h>
+import "C"
+
+// #cgo LDFLAGS: -lz
+// #include <zlib.h>
+import "C"
+
+import (
+	"github.com/daixiang0/gci/a"
+	"fmt"
+)
+`,
+		`package main
+
+import (
+	"fmt"
+)
+
+// #include <stdio.h>
+import "C"
+
+// #cgo LDFLAGS: -lz
+// #include <zlib.h>
+import "C"
+
+import (
+	"github.com/daixiang0/gci/a"
+)
+`,
+	},
+	{
+		"sort-natural",
+
+		`sections:
+  - Standard
+  - Default
+sort: natural
+`,
+
+		`package main
+
+import (
+	"github.com/daixiang0/gci/v10"
+	"github.com/daixiang0/gci/v2"
+	"github.com/daixiang0/gci/v9"
+	"fmt"
+)
+`,
+		`package main
+
+import (
+	"fmt"
+
+	"github.com/daixiang0/gci/v2"
+	"github.com/daixiang0/gci/v9"
+	"github.com/daixiang0/gci/v10"
+)
+`,
+	},
+	{
+		"sort-case-insensitive",
+
+		`sections:
+  - Standard
+  - Default
+sort: case-insensitive
+`,
+
+		`package main
+
+import (
+	"github.com/Azure/go-autorest"
+	"github.com/aws/aws-sdk-go"
+	"github.com/BurntSushi/toml"
+	"github.com/beorn7/perks"
+)
+`,
+		`package main
+
+import (
+	"github.com/aws/aws-sdk-go"
+	"github.com/Azure/go-autorest"
+	"github.com/beorn7/perks"
+	"github.com/BurntSushi/toml"
+)
+`,
+	},
+	{
+		"sort-by-alias",
+
+		`sections:
+  - Standard
+  - Default
+sort: by-alias
+`,
+
+		`package main
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"github.com/daixiang0/gci/zebra"
+	"gopkg.in/yaml.v3"
+	appsv1 "k8s.io/api/apps/v1"
+)
+`,
+		`package main
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"gopkg.in/yaml.v3"
+	"github.com/daixiang0/gci/zebra"
+)
+`,
+	},
+	{
+		"section-sort",
+
+		`sections:
+  - Standard
+  - Default
+  - Prefix(github.com/daixiang0)
+sectionSort:
+  prefix(github.com/daixiang0): natural
+`,
+
+		`package main
+
+import (
+	"github.com/daixiang0/gci/v10"
+	"github.com/daixiang0/gci/v2"
+	"example.com/lib/v10"
+	"example.com/lib/v2"
+)
+`,
+		`package main
+
+import (
+	"example.com/lib/v10"
+	"example.com/lib/v2"
+
+	"github.com/daixiang0/gci/v2"
+	"github.com/daixiang0/gci/v10"
+)
 `,
 	},
 }
@@ -0,0 +1,150 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// RewriteAliases renames the imports of src to the alias that aliases maps their path to, together with every
+// selector of the file that refers to the import, like v1.ObjectMeta. An import is left alone if its new name is
+// already used in the file, as the rename would shadow or be shadowed by that identifier, and so is an import without
+// alias whose package name can not be told, like github.com/hashicorp/go-multierror. It returns the changed
+// source, a description of every change and of every import that could not be renamed. Imports marked with
+// //nolint:gci are left alone.
+func RewriteAliases(src []byte, filename string, aliases map[string]string) ([]byte, []string, []string, error) {
+	if len(aliases) == 0 {
+		return src, nil, nil, nil
+	}
+	fileSet := token.NewFileSet()
+	f, err := parseWithLateImports(fileSet, filename, src)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	tokFile := fileSet.File(f.Pos())
+
+	// the package names used in selectors are left unresolved by the parser, local identifiers are not
+	selectors := map[string][]*ast.Ident{}
+	selectorNames := map[*ast.Ident]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			selectorNames[sel.Sel] = true
+			if x, ok := sel.X.(*ast.Ident); ok && x.Obj == nil {
+				selectors[x.Name] = append(selectors[x.Name], x)
+			}
+		}
+		return true
+	})
+	importNames := map[*ast.Ident]bool{}
+	for _, imp := range f.Imports {
+		if imp.Name != nil {
+			importNames[imp.Name] = true
+		}
+	}
+	taken := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && !selectorNames[ident] && !importNames[ident] {
+			taken[ident.Name] = true
+		}
+		return true
+	})
+	claimed := map[string]bool{}
+	for _, imp := range f.Imports {
+		if name := importName(imp, selectors); name != "" {
+			taken[name] = true
+			claimed[name] = true
+		}
+	}
+	// selectors of a package whose name is not the one its path suggests
+	unclaimed := false
+	for name := range selectors {
+		unclaimed = unclaimed || !claimed[name]
+	}
+
+	var (
+		edits     []edit
+		changes   []string
+		conflicts []string
+	)
+	for _, imp := range f.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil || imp.Path.Value == C || HasNolint(imp.Doc, imp.Comment) {
+			continue
+		}
+		alias, ok := aliases[importPath]
+		if !ok || imp.Name != nil && (imp.Name.Name == "_" || imp.Name.Name == ".") {
+			continue
+		}
+		old := importName(imp, selectors)
+		if old == alias || imp.Name == nil && PackageName(importPath) == alias {
+			continue
+		}
+		if imp.Name == nil && (old == "" || len(selectors[old]) == 0 && unclaimed) {
+			conflicts = append(conflicts, fmt.Sprintf("kept import of %s, the name of its package is not known", imp.Path.Value))
+			continue
+		}
+		if taken[alias] {
+			conflicts = append(conflicts, fmt.Sprintf("kept %s of %s, %s is already used in the file", describeName(imp), imp.Path.Value, alias))
+			continue
+		}
+		taken[alias] = true
+
+		if imp.Name != nil {
+			edits = append(edits, edit{tokFile.Offset(imp.Name.Pos()), tokFile.Offset(imp.Name.End()), alias})
+		} else {
+			edits = append(edits, edit{tokFile.Offset(imp.Path.Pos()), tokFile.Offset(imp.Path.Pos()), alias + " "})
+		}
+		for _, x := range selectors[old] {
+			edits = append(edits, edit{tokFile.Offset(x.Pos()), tokFile.Offset(x.End()), alias})
+		}
+		// the selectors belong to this import, even if the file imports another package with the same name
+		delete(selectors, old)
+		changes = append(changes, fmt.Sprintf("renamed %s of %s to %s", describeName(imp), imp.Path.Value, alias))
+	}
+	if len(edits) == 0 {
+		return src, nil, conflicts, nil
+	}
+
+	// from the last to the first edit, so the offsets stay valid
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	out := src
+	for _, e := range edits {
+		out = splice(out, e.start, e.end, []byte(e.text))
+	}
+	return out, changes, conflicts, nil
+}
+
+// importName returns the name the file refers to the import with. Without alias it is the package name, which is
+// guessed from the import path and the selectors of the file, as a path like k8s.io/api/core/v1 does not tell it.
+func importName(imp *ast.ImportSpec, selectors map[string][]*ast.Ident) string {
+	if imp.Name != nil {
+		return imp.Name.Name
+	}
+	importPath, err := strconv.Unquote(imp.Path.Value)
+	if err != nil {
+		return ""
+	}
+	if name := PackageName(importPath); token.IsIdentifier(name) {
+		return name
+	}
+	// the path ends in a version, the package is named after the version or the element before it
+	candidates := []string{path.Base(importPath), path.Base(path.Dir(importPath))}
+	for _, name := range candidates {
+		if len(selectors[name]) > 0 {
+			return name
+		}
+	}
+	return ""
+}
+
+func describeName(imp *ast.ImportSpec) string {
+	if imp.Name != nil {
+		return "alias " + imp.Name.Name
+	}
+	return "import"
+}
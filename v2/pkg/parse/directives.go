@@ -0,0 +1,197 @@
+package parse
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+const (
+	// IgnoreDirective leaves the whole file as it is
+	IgnoreDirective = "//gci:ignore"
+	// OffDirective starts a region of the import declaration that keeps its order, OnDirective ends it
+	OffDirective = "//gci:off"
+	OnDirective  = "//gci:on"
+)
+
+// IsIgnored reports whether src must not be formatted: it contains a //gci:ignore comment, or a //nolint:gci
+// comment above the package clause or on an import declaration.
+func IsIgnored(src []byte, filename string) (bool, error) {
+	fileSet := token.NewFileSet()
+	f, err := parseWithLateImports(fileSet, filename, src)
+	if err != nil {
+		return false, err
+	}
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			if isDirective(c, IgnoreDirective) {
+				return true, nil
+			}
+		}
+	}
+	if HasNolint(f.Doc) {
+		return true, nil
+	}
+
+	for _, decl := range importDecls(f) {
+		if HasNolint(decl.Doc) {
+			return true, nil
+		}
+		if !decl.Lparen.IsValid() {
+			// the comment at the end of import "pkg" belongs to the import
+			continue
+		}
+		// e.g. import ( //nolint:gci
+		line := fileSet.Position(decl.Lparen).Line
+		for _, group := range f.Comments {
+			if fileSet.Position(group.Pos()).Line == line && group.Pos() > decl.Lparen && HasNolint(group) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// HasNolint reports whether one of the comments is a nolint directive for gci, like //nolint or //nolint:gci,lll.
+func HasNolint(groups ...*ast.CommentGroup) bool {
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		for _, c := range group.List {
+			if isNolint(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isNolint(text string) bool {
+	rest, found := strings.CutPrefix(text, "//nolint")
+	if !found {
+		return false
+	}
+	if rest == "" || rest[0] == ' ' || rest[0] == '\t' {
+		// applies to every linter
+		return true
+	}
+	if rest[0] != ':' {
+		return false
+	}
+	linters, _, _ := strings.Cut(rest[1:], " ")
+	for _, linter := range strings.Split(linters, ",") {
+		if linter == "gci" || linter == "all" {
+			return true
+		}
+	}
+	return false
+}
+
+func isDirective(c *ast.Comment, directive string) bool {
+	return strings.TrimRight(c.Text, " \t") == directive
+}
+
+// ImportSegment is a range of lines of the body of an import declaration. Pinned segments keep their order, the
+// imports of the others are sorted as one group.
+type ImportSegment struct {
+	Start, End int
+	Pinned     bool
+}
+
+// PinnedSegments splits the body of the import declaration of src, which must be the only one besides import "C",
+// into segments, if any of its imports are pinned: the ones between //gci:off and //gci:on, or till the end of the
+// declaration, and the ones marked with //nolint:gci. It returns nil if there are none.
+func PinnedSegments(src []byte, filename string) ([]ImportSegment, error) {
+	fileSet := token.NewFileSet()
+	f, err := parseWithLateImports(fileSet, filename, src)
+	if err != nil {
+		return nil, err
+	}
+	tokFile := fileSet.File(f.Pos())
+	decls := importDecls(f)
+	if len(decls) != 1 || !decls[0].Lparen.IsValid() {
+		return nil, nil
+	}
+	decl := decls[0]
+	bodyStart, bodyEnd := lineEnd(src, tokFile.Offset(decl.Lparen)), lineStart(src, tokFile.Offset(decl.Rparen))
+	if bodyStart >= bodyEnd {
+		return nil, nil
+	}
+
+	var pinned []ImportSegment
+	regionStart := -1
+	for _, group := range f.Comments {
+		start, end := tokFile.Offset(group.Pos()), tokFile.Offset(group.End())
+		if start < bodyStart || end > bodyEnd {
+			continue
+		}
+		for i, c := range group.List {
+			switch {
+			case isDirective(c, OffDirective) && regionStart < 0:
+				regionStart = lineStart(src, tokFile.Offset(c.Pos()))
+				// the comment lines above //gci:off belong to the region, e.g. the doc of its first import
+				for j := i - 1; j >= 0; j-- {
+					commentStart := tokFile.Offset(group.List[j].Pos())
+					if strings.TrimSpace(string(src[lineStart(src, commentStart):commentStart])) != "" {
+						break
+					}
+					regionStart = lineStart(src, commentStart)
+				}
+			case isDirective(c, OnDirective) && regionStart >= 0:
+				pinned = append(pinned, ImportSegment{regionStart, lineEnd(src, tokFile.Offset(c.End())), true})
+				regionStart = -1
+			}
+		}
+	}
+	if regionStart >= 0 {
+		pinned = append(pinned, ImportSegment{regionStart, bodyEnd, true})
+	}
+	for _, spec := range decl.Specs {
+		imp := spec.(*ast.ImportSpec)
+		if HasNolint(imp.Doc, imp.Comment) {
+			start, end := specRange(tokFile, imp)
+			pinned = append(pinned, ImportSegment{lineStart(src, start), lineEnd(src, end), true})
+		}
+	}
+	if len(pinned) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(pinned, func(i, j int) bool {
+		return pinned[i].Start < pinned[j].Start
+	})
+	var segments []ImportSegment
+	pos := bodyStart
+	for _, p := range pinned {
+		if n := len(segments); n > 0 && p.Start <= pos {
+			// overlaps or touches the previous region
+			segments[n-1].End = max(segments[n-1].End, p.End)
+			pos = segments[n-1].End
+			continue
+		}
+		if p.Start > pos {
+			segments = append(segments, ImportSegment{pos, p.Start, false})
+		}
+		segments = append(segments, p)
+		pos = p.End
+	}
+	if pos < bodyEnd {
+		segments = append(segments, ImportSegment{pos, bodyEnd, false})
+	}
+	return segments, nil
+}
+
+// lineStart returns the offset of the start of the line of offset.
+func lineStart(src []byte, offset int) int {
+	return strings.LastIndexByte(string(src[:offset]), '\n') + 1
+}
+
+// lineEnd returns the offset after the linebreak of the line of offset.
+func lineEnd(src []byte, offset int) int {
+	if i := strings.IndexByte(string(src[offset:]), '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(src)
+}
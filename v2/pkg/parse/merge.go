@@ -0,0 +1,211 @@
+package parse
+
+import (
+	"bytes"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// lateImportError is reported by go/parser for imports after other declarations, which old code generators emit.
+const lateImportError = "imports must appear before other declarations"
+
+// ImportDecl is the range of an import declaration in the source, including the comment at the end of its line.
+type ImportDecl struct {
+	Start, End int
+}
+
+// MergeImportDecls merges all import declarations of src into the first one. Declarations between the imports, like
+// const and var blocks, stay where they are, so they end up below the merged imports. Doc comments of the merged
+// declarations and comments that do not belong to an import are kept inside of the block.
+// import "C" declarations are left alone, as their doc comment is the cgo preamble.
+func MergeImportDecls(src []byte, filename string) ([]byte, error) {
+	fileSet := token.NewFileSet()
+	f, err := parseWithLateImports(fileSet, filename, src)
+	if err != nil {
+		return nil, err
+	}
+	tokFile := fileSet.File(f.Pos())
+
+	decls := importDecls(f)
+	if len(decls) <= 1 {
+		return src, nil
+	}
+
+	var block strings.Builder
+	block.WriteString("import (\n")
+	for i, decl := range decls {
+		start, end := declRange(tokFile, src, decl)
+		var items []ImportDecl
+		if i > 0 && decl.Doc != nil {
+			items = append(items, ImportDecl{tokFile.Offset(decl.Doc.Pos()), tokFile.Offset(decl.Doc.End())})
+		}
+		for _, spec := range decl.Specs {
+			specStart, specEnd := specRange(tokFile, spec.(*ast.ImportSpec))
+			items = append(items, ImportDecl{specStart, specEnd})
+		}
+		// comments inside of the parentheses which do not belong to an import
+		for _, comment := range f.Comments {
+			commentStart, commentEnd := tokFile.Offset(comment.Pos()), tokFile.Offset(comment.End())
+			if commentStart > start && commentEnd <= end && !covered(items, commentStart) {
+				items = append(items, ImportDecl{commentStart, commentEnd})
+			}
+		}
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].Start < items[j].Start
+		})
+		for _, item := range items {
+			for _, line := range strings.Split(string(src[item.Start:item.End]), "\n") {
+				block.WriteString("\t" + strings.TrimLeft(line, " \t") + "\n")
+			}
+		}
+	}
+	block.WriteString(")")
+
+	// replace the declarations from the last to the first one, so the offsets stay valid
+	out := src
+	for i := len(decls) - 1; i >= 0; i-- {
+		start, end := declRange(tokFile, src, decls[i])
+		if i == 0 {
+			out = splice(out, start, end, []byte(block.String()))
+			continue
+		}
+		if decls[i].Doc != nil {
+			start = tokFile.Offset(decls[i].Doc.Pos())
+		}
+		// drop the linebreak of the removed declaration
+		if end < len(out) && out[end] == '\n' {
+			end++
+		}
+		out = splice(out, start, end, nil)
+	}
+	return out, nil
+}
+
+// ImportDecls returns the ranges of the import declarations of src, except for import "C".
+func ImportDecls(src []byte, filename string) ([]ImportDecl, error) {
+	fileSet := token.NewFileSet()
+	f, err := parseWithLateImports(fileSet, filename, src)
+	if err != nil {
+		return nil, err
+	}
+	tokFile := fileSet.File(f.Pos())
+
+	var ranges []ImportDecl
+	for _, decl := range importDecls(f) {
+		start, end := declRange(tokFile, src, decl)
+		ranges = append(ranges, ImportDecl{start, end})
+	}
+	return ranges, nil
+}
+
+// Format formats src like gofmt, but accepts imports after other declarations.
+func Format(src []byte, filename string) ([]byte, error) {
+	return format(src, filename, true)
+}
+
+// FormatKeepingImportOrder formats src like Format, but keeps the order of the imports, which gofmt sorts by path.
+func FormatKeepingImportOrder(src []byte, filename string) ([]byte, error) {
+	return format(src, filename, false)
+}
+
+func format(src []byte, filename string, sortImports bool) ([]byte, error) {
+	fileSet := token.NewFileSet()
+	f, err := parseWithLateImports(fileSet, filename, src)
+	if err != nil {
+		return nil, err
+	}
+	if sortImports {
+		ast.SortImports(fileSet, f)
+	}
+	// the configuration of gofmt, go/format can not be used as it rejects the late imports
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	var buf bytes.Buffer
+	if err := cfg.Fprint(&buf, fileSet, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseWithLateImports parses src, but accepts imports after other declarations.
+func parseWithLateImports(fileSet *token.FileSet, filename string, src []byte) (*ast.File, error) {
+	f, err := parser.ParseFile(fileSet, filename, src, parser.ParseComments)
+	var errs scanner.ErrorList
+	if !errors.As(err, &errs) {
+		return f, err
+	}
+	for _, e := range errs {
+		if e.Msg != lateImportError {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func importDecls(f *ast.File) []*ast.GenDecl {
+	var decls []*ast.GenDecl
+	for _, decl := range f.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.IMPORT || len(genDecl.Specs) == 0 {
+			continue
+		}
+		isC := false
+		for _, spec := range genDecl.Specs {
+			isC = isC || spec.(*ast.ImportSpec).Path.Value == C
+		}
+		if !isC {
+			decls = append(decls, genDecl)
+		}
+	}
+	return decls
+}
+
+// declRange returns the range of the declaration without its doc comment, but with the comment at the end of its line.
+func declRange(tokFile *token.File, src []byte, decl *ast.GenDecl) (int, int) {
+	start, end := tokFile.Offset(decl.Pos()), tokFile.Offset(decl.End())
+	for _, spec := range decl.Specs {
+		if _, specEnd := specRange(tokFile, spec.(*ast.ImportSpec)); specEnd > end {
+			end = specEnd
+		}
+	}
+	// e.g. import ( "fmt" ) // comment
+	if rest := src[end:]; len(rest) > 0 {
+		line, _, _ := strings.Cut(string(rest), "\n")
+		if trimmed := strings.TrimLeft(line, " \t"); strings.HasPrefix(trimmed, "//") {
+			end += len(line)
+		}
+	}
+	return start, end
+}
+
+func specRange(tokFile *token.File, spec *ast.ImportSpec) (int, int) {
+	start, end := spec.Pos(), spec.End()
+	if spec.Doc != nil {
+		start = spec.Doc.Pos()
+	}
+	if spec.Comment != nil {
+		end = spec.Comment.End()
+	}
+	return tokFile.Offset(start), tokFile.Offset(end)
+}
+
+func covered(items []ImportDecl, offset int) bool {
+	for _, item := range items {
+		if offset >= item.Start && offset < item.End {
+			return true
+		}
+	}
+	return false
+}
+
+func splice(src []byte, start, end int, replacement []byte) []byte {
+	out := make([]byte, 0, len(src)-(end-start)+len(replacement))
+	out = append(out, src[:start]...)
+	out = append(out, replacement...)
+	return append(out, src[end:]...)
+}
@@ -0,0 +1,102 @@
+package parse
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// SortMode defines the order of the imports within a section.
+type SortMode string
+
+const (
+	// SortLexical orders the imports by path like gofmt, e.g. example.com/v10 before example.com/v2
+	SortLexical SortMode = "lexical"
+	// SortNatural orders the numbers within the paths by their value, e.g. example.com/v2 before example.com/v10
+	SortNatural SortMode = "natural"
+	// SortCaseInsensitive orders the imports by path regardless of case, e.g. github.com/Azure next to github.com/aws
+	SortCaseInsensitive SortMode = "case-insensitive"
+	// SortByAlias orders the imports by the name the file refers to them with, which is the package name without alias
+	SortByAlias SortMode = "by-alias"
+)
+
+func ParseSortMode(in string) (SortMode, error) {
+	switch m := SortMode(in); m {
+	case "":
+		return SortLexical, nil
+	case SortLexical, SortNatural, SortCaseInsensitive, SortByAlias:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid sort mode %q, must be one of %s, %s, %s or %s", in, SortLexical, SortNatural, SortCaseInsensitive, SortByAlias)
+	}
+}
+
+// Compare returns a negative number if a goes before b, a positive one if it goes after b and 0 if both are equal.
+// Imports that are equal in the order of the mode are ordered lexically, so the result does not depend on the
+// order the imports were in before.
+func (m SortMode) Compare(a, b *GciImports) int {
+	var c int
+	switch m {
+	case SortNatural:
+		c = compareNatural(a.Path, b.Path)
+	case SortCaseInsensitive:
+		c = strings.Compare(strings.ToLower(a.Path), strings.ToLower(b.Path))
+	case SortByAlias:
+		c = strings.Compare(sortName(a), sortName(b))
+	}
+	if c != 0 {
+		return c
+	}
+	if c = strings.Compare(a.Path, b.Path); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Name, b.Name)
+}
+
+// sortName is the alias of the import, or the name of its package for imports without alias, blank and dot imports.
+func sortName(imp *GciImports) string {
+	if imp.Name != "" && imp.Name != "_" && imp.Name != "." {
+		return imp.Name
+	}
+	if name := PackageName(imp.Path); name != "" {
+		return name
+	}
+	return path.Base(imp.Path)
+}
+
+// compareNatural compares a and b like strings.Compare, but compares runs of digits by their value.
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			numA, restA := cutDigits(a)
+			numB, restB := cutDigits(b)
+			// leading zeros do not change the value, they only decide in the end
+			trimmedA, trimmedB := strings.TrimLeft(numA, "0"), strings.TrimLeft(numB, "0")
+			if len(trimmedA) != len(trimmedB) {
+				return len(trimmedA) - len(trimmedB)
+			}
+			if c := strings.Compare(trimmedA, trimmedB); c != 0 {
+				return c
+			}
+			a, b = restA, restB
+			continue
+		}
+		if a[0] != b[0] {
+			return int(a[0]) - int(b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) - len(b)
+}
+
+func cutDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
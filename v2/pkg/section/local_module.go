@@ -1,9 +1,13 @@
 package section
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 
 	"golang.org/x/mod/modfile"
 
@@ -15,17 +19,45 @@ const LocalModuleType = "localmodule"
 
 type LocalModule struct {
 	Path string
+	// Workspace holds the other modules of the go.work file, they only match with ScopeWorkspace
+	Workspace []string
+	// Scope limits the modules to the module of the file or widens them to its workspace
+	Scope LocalModuleScope
 }
 
+// LocalModuleScope selects which modules are local for a file.
+type LocalModuleScope string
+
+const (
+	// ScopeSelf is only the module the file belongs to, it is more specific than the other scopes
+	ScopeSelf LocalModuleScope = "self"
+	// ScopeWorkspace is every module of the go.work file the module of the file is used in
+	ScopeWorkspace LocalModuleScope = "workspace"
+)
+
 func (m *LocalModule) MatchSpecificity(spec *parse.GciImports) specificity.MatchSpecificity {
-	if spec.Path == m.Path || strings.HasPrefix(spec.Path, m.Path+"/") {
-		return specificity.LocalModule{}
+	if belongsTo(spec.Path, m.Path) {
+		return specificity.LocalModule{Self: m.Scope == ScopeSelf}
+	}
+	if m.Scope == ScopeWorkspace {
+		for _, path := range m.Workspace {
+			if belongsTo(spec.Path, path) {
+				return specificity.LocalModule{}
+			}
+		}
 	}
 
 	return specificity.MisMatch{}
 }
 
+func belongsTo(pkgPath, modPath string) bool {
+	return pkgPath == modPath || strings.HasPrefix(pkgPath, modPath+"/")
+}
+
 func (m *LocalModule) String() string {
+	if m.Scope != "" {
+		return LocalModuleType + "(" + string(m.Scope) + ")"
+	}
 	return LocalModuleType
 }
 
@@ -33,25 +65,120 @@ func (m *LocalModule) Type() string {
 	return LocalModuleType
 }
 
+// Configure sets the module path if path is given, otherwise it is looked up for every file, see ForFile.
 func (m *LocalModule) Configure(path string) error {
 	if path != "" {
 		m.Path = path
-	} else {
-		path, err := findLocalModule()
-		if err != nil {
-			return fmt.Errorf("finding local modules for `localModule` configuration: %w", err)
-		}
-		m.Path = path
 	}
 
 	return nil
 }
 
-func findLocalModule() (string, error) {
-	b, err := os.ReadFile("go.mod")
+// ForFile returns the section for the file at filePath. Unless the module path was configured, it is
+// read from the nearest go.mod in the directory of the file or one of its parents, and for ScopeWorkspace
+// the other modules from the nearest go.work.
+func (m *LocalModule) ForFile(filePath string) (*LocalModule, error) {
+	if m.Path != "" {
+		return m, nil
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return nil, err
+	}
+	paths, err := modulePaths.lookup(dir)
 	if err != nil {
-		return "", fmt.Errorf("reading go.mod: %w", err)
+		return nil, fmt.Errorf("finding local modules for `localModule` configuration of %s: %w", filePath, err)
+	}
+	fileModule := &LocalModule{Path: paths[0], Scope: m.Scope}
+
+	if m.Scope == ScopeWorkspace {
+		workspace, err := workspacePaths.lookup(dir)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// a module without workspace
+		case err != nil:
+			return nil, fmt.Errorf("finding workspace modules for `localModule` configuration of %s: %w", filePath, err)
+		case slices.Contains(workspace, fileModule.Path):
+			fileModule.Workspace = workspace
+		}
 	}
 
-	return modfile.ModulePath(b), nil
+	return fileModule, nil
+}
+
+// modulePaths caches the module path of every directory, so each go.mod is read only once per run.
+var modulePaths = moduleCache{name: "go.mod", find: findLocalModule, dirs: map[string][]string{}}
+
+// workspacePaths caches the modules of the go.work file of every directory.
+var workspacePaths = moduleCache{name: "go.work", find: findWorkspaceModules, dirs: map[string][]string{}}
+
+type moduleCache struct {
+	name string
+	// find returns the module paths defined in dir or an error wrapping os.ErrNotExist if there is no file
+	find func(dir string) ([]string, error)
+	lock sync.Mutex
+	dirs map[string][]string
+}
+
+func (c *moduleCache) lookup(dir string) ([]string, error) {
+	c.lock.Lock()
+	paths, ok := c.dirs[dir]
+	c.lock.Unlock()
+	if ok {
+		return paths, nil
+	}
+
+	paths, err := c.find(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, fmt.Errorf("no %s found: %w", c.name, err)
+		}
+		paths, err = c.lookup(parent)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	c.dirs[dir] = paths
+	c.lock.Unlock()
+	return paths, nil
+}
+
+func findLocalModule(dir string) ([]string, error) {
+	b, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, fmt.Errorf("reading go.mod: %w", err)
+	}
+
+	return []string{modfile.ModulePath(b)}, nil
+}
+
+func findWorkspaceModules(dir string) ([]string, error) {
+	workFilePath := filepath.Join(dir, "go.work")
+	b, err := os.ReadFile(workFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("reading go.work: %w", err)
+	}
+	workFile, err := modfile.ParseWork(workFilePath, b, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parsing go.work: %w", err)
+	}
+
+	var paths []string
+	for _, use := range workFile.Use {
+		modDir := use.Path
+		if !filepath.IsAbs(modDir) {
+			modDir = filepath.Join(dir, modDir)
+		}
+		b, err := os.ReadFile(filepath.Join(modDir, "go.mod"))
+		if err != nil {
+			// a missing module must not be taken for a missing go.work
+			return nil, fmt.Errorf("reading go.mod of %s used in go.work: %v", use.Path, err)
+		}
+		paths = append(paths, modfile.ModulePath(b))
+	}
+	return paths, nil
 }
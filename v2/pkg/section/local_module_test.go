@@ -1,8 +1,11 @@
 package section
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/daixiang0/gci/v2/pkg/parse"
 	"github.com/daixiang0/gci/v2/pkg/specificity"
 )
 
@@ -16,3 +19,106 @@ func TestLocalModule_specificity(t *testing.T) {
 	}
 	testSpecificity(t, testCases)
 }
+
+func TestLocalModule_ForFile(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(filepath.Join(root, "services", "api", "go.mod"), "module example.com/api\n")
+	writeFile(filepath.Join(root, "services", "worker", "go.mod"), "module example.com/worker\n")
+
+	for file, expected := range map[string]string{
+		filepath.Join(root, "services", "api", "main.go"):                 "example.com/api",
+		filepath.Join(root, "services", "api", "internal", "foo", "x.go"): "example.com/api",
+		filepath.Join(root, "services", "worker", "cmd", "main.go"):       "example.com/worker",
+	} {
+		fileModule, err := new(LocalModule).ForFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fileModule.Path != expected {
+			t.Errorf("%s: got %q, want %q", file, fileModule.Path, expected)
+		}
+	}
+
+	// the module of a directory is only read once
+	writeFile(filepath.Join(root, "services", "api", "go.mod"), "module example.com/changed\n")
+	fileModule, err := new(LocalModule).ForFile(filepath.Join(root, "services", "api", "other.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileModule.Path != "example.com/api" {
+		t.Errorf("got %q, want the cached module example.com/api", fileModule.Path)
+	}
+
+	configured := &LocalModule{Path: "example.com/configured"}
+	if fileModule, _ := configured.ForFile(filepath.Join(root, "services", "api", "main.go")); fileModule != configured {
+		t.Errorf("configured module path was replaced with %q", fileModule.Path)
+	}
+
+	if _, err := new(LocalModule).ForFile(filepath.Join(root, "main.go")); err == nil {
+		t.Error("expected error for file outside of a module")
+	}
+}
+
+func TestLocalModule_ForFileScopes(t *testing.T) {
+	root := t.TempDir()
+	for path, content := range map[string]string{
+		"go.work":        "go 1.21\n\nuse (\n\t./app\n\t./lib\n)\n",
+		"app/go.mod":     "module example.com/app\n",
+		"lib/go.mod":     "module example.com/lib\n",
+		"nested/go.mod":  "module example.com/nested\n",
+		"app/cmd/.keep":  "",
+		"nested/x/.keep": "",
+	} {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	self, err := (&LocalModule{Scope: ScopeSelf}).ForFile(filepath.Join(root, "app", "cmd", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspace, err := (&LocalModule{Scope: ScopeWorkspace}).ForFile(filepath.Join(root, "app", "cmd", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pkgPath, expected := range map[string][2]specificity.MatchSpecificity{
+		"example.com/app/internal": {specificity.LocalModule{Self: true}, specificity.LocalModule{}},
+		"example.com/lib":          {specificity.MisMatch{}, specificity.LocalModule{}},
+		"example.com/other":        {specificity.MisMatch{}, specificity.MisMatch{}},
+	} {
+		spec := &parse.GciImports{Path: pkgPath}
+		if got := self.MatchSpecificity(spec); !got.Equal(expected[0]) {
+			t.Errorf("self %s: got %v, want %v", pkgPath, got, expected[0])
+		}
+		if got := workspace.MatchSpecificity(spec); !got.Equal(expected[1]) {
+			t.Errorf("workspace %s: got %v, want %v", pkgPath, got, expected[1])
+		}
+	}
+	if !self.MatchSpecificity(&parse.GciImports{Path: "example.com/app"}).IsMoreSpecific(workspace.MatchSpecificity(&parse.GciImports{Path: "example.com/app"})) {
+		t.Error("expected self to be more specific than workspace")
+	}
+
+	// a module which is not used in the go.work file has no workspace
+	nested, err := (&LocalModule{Scope: ScopeWorkspace}).ForFile(filepath.Join(root, "nested", "x", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nested.Path != "example.com/nested" || len(nested.Workspace) != 0 {
+		t.Errorf("got %q with workspace %v, want example.com/nested without workspace", nested.Path, nested.Workspace)
+	}
+}
@@ -45,8 +45,15 @@ func Parse(sectionStrings []string) (SectionList, error) {
 			section = Dot{}
 		case AliasType:
 			section = Alias{}
+		case CgoType:
+			section = Cgo{}
 		case LocalModuleType:
-			section = &LocalModule{}
+			switch scope := LocalModuleScope(strings.ToLower(strings.TrimSpace(sectionParams))); scope {
+			case "", ScopeSelf, ScopeWorkspace:
+				section = &LocalModule{Scope: scope}
+			default:
+				return nil, fmt.Errorf("invalid localmodule scope %q, must be self or workspace", sectionParams)
+			}
 		case NewLineType:
 			section = NewLine{}
 		default:
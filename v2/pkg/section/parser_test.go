@@ -43,6 +43,16 @@ func TestParse(t *testing.T) {
 			expectedSection: SectionList{Custom{Prefix: "domainA,domainB"}},
 			expectedError:   "",
 		},
+		{
+			input:           []string{"localmodule(self)", "LocalModule(Workspace)"},
+			expectedSection: SectionList{&LocalModule{Scope: ScopeSelf}, &LocalModule{Scope: ScopeWorkspace}},
+			expectedError:   "",
+		},
+		{
+			input:           []string{"localmodule(other)"},
+			expectedSection: nil,
+			expectedError:   `invalid localmodule scope "other", must be self or workspace`,
+		},
 	}
 	for _, test := range testCases {
 		parsedSection, err := Parse(test.input)
@@ -1,6 +1,8 @@
 package section
 
 import (
+	"slices"
+
 	"github.com/daixiang0/gci/v2/pkg/parse"
 	"github.com/daixiang0/gci/v2/pkg/specificity"
 )
@@ -28,3 +30,26 @@ func DefaultSections() SectionList {
 func DefaultSectionSeparators() SectionList {
 	return SectionList{NewLine{}}
 }
+
+// ForFile returns the sections to format the file at path with, see LocalModule.ForFile.
+func (list SectionList) ForFile(path string) (SectionList, error) {
+	var result SectionList
+	for i, s := range list {
+		m, ok := s.(*LocalModule)
+		if !ok {
+			continue
+		}
+		fileModule, err := m.ForFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			result = slices.Clone(list)
+		}
+		result[i] = fileModule
+	}
+	if result == nil {
+		return list, nil
+	}
+	return result, nil
+}
@@ -93,7 +93,10 @@ func (n NameMatch) Equal(other MatchSpecificity) bool {
 	return ok
 }
 
-type LocalModule struct{}
+// LocalModule is a match of a local module, Self is a match of the module of the file itself.
+type LocalModule struct {
+	Self bool
+}
 
 func (l LocalModule) IsMoreSpecific(other MatchSpecificity) bool {
 	_, isMisMatch := other.(MisMatch)
@@ -101,10 +104,11 @@ func (l LocalModule) IsMoreSpecific(other MatchSpecificity) bool {
 	_, isStandard := other.(StandardMatch)
 	_, isMatch := other.(Match)
 	_, isName := other.(NameMatch)
-	return isMisMatch || isDefault || isStandard || isMatch || isName
+	otherLocalModule, isLocalModule := other.(LocalModule)
+	return isMisMatch || isDefault || isStandard || isMatch || isName || (isLocalModule && l.Self && !otherLocalModule.Self)
 }
 
 func (l LocalModule) Equal(other MatchSpecificity) bool {
-	_, ok := other.(LocalModule)
-	return ok
+	otherLocalModule, ok := other.(LocalModule)
+	return ok && l.Self == otherLocalModule.Self
 }
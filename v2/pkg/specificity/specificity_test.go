@@ -22,5 +22,5 @@ func TestSpecificityEquality(t *testing.T) {
 }
 
 func testCasesInSpecificityOrder() []MatchSpecificity {
-	return []MatchSpecificity{MisMatch{}, DefaultMatch{}, StandardMatch{}, Match{Length: 0}, Match{Length: 1}}
+	return []MatchSpecificity{MisMatch{}, DefaultMatch{}, StandardMatch{}, Match{Length: 0}, Match{Length: 1}, LocalModule{}, LocalModule{Self: true}}
 }